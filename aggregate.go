@@ -2,10 +2,10 @@ package elasticsearch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"path"
 )
 
 // TermAggregations is a list of TermAggregation, allowing
@@ -55,6 +55,13 @@ type Bucket struct {
 
 // TermAggregate term aggregates in a specific index. A query is optional.
 func (c *Client) TermAggregate(index, doctype string, query map[string]interface{}, aggregations TermAggregations) (TermAggregationResults, error) {
+	return c.TermAggregateContext(context.Background(), index, doctype, query, aggregations)
+}
+
+// TermAggregateContext is like TermAggregate, but aborts the request when ctx is done.
+func (c *Client) TermAggregateContext(ctx context.Context, index, doctype string, query map[string]interface{}, aggregations TermAggregations) (TermAggregationResults, error) {
+	ctx, span := c.startOperation(ctx, APIAggregation, "TermAggregate", index, doctype)
+	defer span.End()
 	request := map[string]interface{}{
 		"size": 0,
 		"aggs": aggregations,
@@ -64,12 +71,12 @@ func (c *Client) TermAggregate(index, doctype string, query map[string]interface
 	}
 	b, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("could not marshal request: %s", err)
+		return nil, fmt.Errorf("could not marshal request: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_search"
-	res, err := c.get(apipath, b)
+	apipath := c.indexPath(index, doctype) + "/_search"
+	res, err := c.get(ctx, apipath, b)
 	if err != nil {
-		return nil, fmt.Errorf("could not get aggregations: %s", err)
+		return nil, fmt.Errorf("could not get aggregations: %w", err)
 	}
 	result := struct {
 		Aggregations TermAggregationResults `json:"aggregations"`
@@ -77,7 +84,7 @@ func (c *Client) TermAggregate(index, doctype string, query map[string]interface
 	decoder := json.NewDecoder(bytes.NewReader(res))
 	decoder.UseNumber()
 	if err := decoder.Decode(&result); err != nil {
-		return nil, fmt.Errorf("could not decode result: %s", err)
+		return nil, fmt.Errorf("could not decode result: %w", err)
 	}
 	return result.Aggregations, nil
 }
@@ -85,6 +92,13 @@ func (c *Client) TermAggregate(index, doctype string, query map[string]interface
 // RangeAggregate returns the min- and max-value for a specific field in a specific index.
 // A query is optional.
 func (c *Client) RangeAggregate(index, doctype string, query map[string]interface{}, field string) (float64, float64, error) {
+	return c.RangeAggregateContext(context.Background(), index, doctype, query, field)
+}
+
+// RangeAggregateContext is like RangeAggregate, but aborts the request when ctx is done.
+func (c *Client) RangeAggregateContext(ctx context.Context, index, doctype string, query map[string]interface{}, field string) (float64, float64, error) {
+	ctx, span := c.startOperation(ctx, APIAggregation, "RangeAggregate", index, doctype)
+	defer span.End()
 	request := map[string]interface{}{
 		"size": 0,
 		"aggs": map[string]interface{}{
@@ -105,12 +119,12 @@ func (c *Client) RangeAggregate(index, doctype string, query map[string]interfac
 	}
 	b, err := json.Marshal(request)
 	if err != nil {
-		return 0, 0, fmt.Errorf("could not marshal request: %s", err)
+		return 0, 0, fmt.Errorf("could not marshal request: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_search"
-	res, err := c.get(apipath, b)
+	apipath := c.indexPath(index, doctype) + "/_search"
+	res, err := c.get(ctx, apipath, b)
 	if err != nil {
-		return 0, 0, fmt.Errorf("could not get aggregations: %s", err)
+		return 0, 0, fmt.Errorf("could not get aggregations: %w", err)
 	}
 	result := struct {
 		Aggregations map[string]struct {
@@ -119,10 +133,10 @@ func (c *Client) RangeAggregate(index, doctype string, query map[string]interfac
 	}{}
 	decoder := json.NewDecoder(bytes.NewReader(res))
 	if err := decoder.Decode(&result); err != nil {
-		return 0, 0, fmt.Errorf("could not decode result: %s", err)
+		return 0, 0, fmt.Errorf("could not decode result: %w", err)
 	}
 	if result.Aggregations == nil {
-		return 0, 0, fmt.Errorf("no aggregation result found: %s", err)
+		return 0, 0, errors.New("no aggregation result found")
 	}
 	minValue, ok1 := result.Aggregations["min_"+field]
 	maxValue, ok2 := result.Aggregations["max_"+field]
@@ -135,6 +149,13 @@ func (c *Client) RangeAggregate(index, doctype string, query map[string]interfac
 // CardinalityAggregate returns the unique count of a specific field in a specific index.
 // A query is optional.
 func (c *Client) CardinalityAggregate(index, doctype string, query map[string]interface{}, field string) (int64, error) {
+	return c.CardinalityAggregateContext(context.Background(), index, doctype, query, field)
+}
+
+// CardinalityAggregateContext is like CardinalityAggregate, but aborts the request when ctx is done.
+func (c *Client) CardinalityAggregateContext(ctx context.Context, index, doctype string, query map[string]interface{}, field string) (int64, error) {
+	ctx, span := c.startOperation(ctx, APIAggregation, "CardinalityAggregate", index, doctype)
+	defer span.End()
 	request := map[string]interface{}{
 		"size": 0,
 		"aggs": map[string]interface{}{
@@ -150,12 +171,12 @@ func (c *Client) CardinalityAggregate(index, doctype string, query map[string]in
 	}
 	b, err := json.Marshal(request)
 	if err != nil {
-		return 0, fmt.Errorf("could not marshal request: %s", err)
+		return 0, fmt.Errorf("could not marshal request: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_search"
-	res, err := c.get(apipath, b)
+	apipath := c.indexPath(index, doctype) + "/_search"
+	res, err := c.get(ctx, apipath, b)
 	if err != nil {
-		return 0, fmt.Errorf("could not get aggregations: %s", err)
+		return 0, fmt.Errorf("could not get aggregations: %w", err)
 	}
 	result := struct {
 		Aggregations map[string]struct {
@@ -164,7 +185,7 @@ func (c *Client) CardinalityAggregate(index, doctype string, query map[string]in
 	}{}
 	decoder := json.NewDecoder(bytes.NewReader(res))
 	if err := decoder.Decode(&result); err != nil {
-		return 0, fmt.Errorf("could not decode result: %s", err)
+		return 0, fmt.Errorf("could not decode result: %w", err)
 	}
 	value, ok := result.Aggregations["count_"+field]
 	if !ok {
@@ -174,12 +195,23 @@ func (c *Client) CardinalityAggregate(index, doctype string, query map[string]in
 }
 
 func (c *Client) CompositeAggregate(index, doctype string, query map[string]interface{}, field string) ([]*Bucket, error) {
-	return c.compositeAggregateAfter(index, doctype, query, field, nil)
+	return c.CompositeAggregateContext(context.Background(), index, doctype, query, field)
+}
+
+// CompositeAggregateContext is like CompositeAggregate, but stops paging through
+// the buckets when ctx is done.
+func (c *Client) CompositeAggregateContext(ctx context.Context, index, doctype string, query map[string]interface{}, field string) ([]*Bucket, error) {
+	ctx, span := c.startOperation(ctx, APIAggregation, "CompositeAggregate", index, doctype)
+	defer span.End()
+	return c.compositeAggregateAfter(ctx, index, doctype, query, field, nil)
 }
 
 var compositeSize = 500
 
-func (c *Client) compositeAggregateAfter(index, doctype string, query map[string]interface{}, field string, after interface{}) ([]*Bucket, error) {
+func (c *Client) compositeAggregateAfter(ctx context.Context, index, doctype string, query map[string]interface{}, field string, after interface{}) ([]*Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	var compositeResult []*Bucket
 	request := map[string]interface{}{
 		"size": 0,
@@ -206,12 +238,12 @@ func (c *Client) compositeAggregateAfter(index, doctype string, query map[string
 	}
 	b, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("could not marshal request: %s", err)
+		return nil, fmt.Errorf("could not marshal request: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_search"
-	res, err := c.post(apipath, b)
+	apipath := c.indexPath(index, doctype) + "/_search"
+	res, err := c.post(ctx, apipath, b)
 	if err != nil {
-		return nil, fmt.Errorf("could not get aggregations: %s", err)
+		return nil, fmt.Errorf("could not get aggregations: %w", err)
 	}
 	result := struct {
 		Aggregations struct {
@@ -226,13 +258,13 @@ func (c *Client) compositeAggregateAfter(index, doctype string, query map[string
 	decoder := json.NewDecoder(bytes.NewReader(res))
 	decoder.UseNumber()
 	if err := decoder.Decode(&result); err != nil {
-		return nil, fmt.Errorf("could not decode result: %s", err)
+		return nil, fmt.Errorf("could not decode result: %w", err)
 	}
 	for _, bucket := range result.Aggregations.MyBuckets.Buckets {
 		compositeResult = append(compositeResult, &Bucket{Key: bucket.Key[field], Count: bucket.Count})
 	}
 	if bucketLength := len(result.Aggregations.MyBuckets.Buckets); bucketLength > 0 {
-		nextResult, err := c.compositeAggregateAfter(index, doctype, query, field, map[string]interface{}{
+		nextResult, err := c.compositeAggregateAfter(ctx, index, doctype, query, field, map[string]interface{}{
 			field: result.Aggregations.MyBuckets.Buckets[bucketLength-1].Key[field],
 		})
 		if err != nil {
@@ -255,7 +287,31 @@ const (
 	DateHistogramIntervalAuto   = "auto"
 )
 
+// dateHistogram returns the date_histogram aggregation for the interval. Since
+// Elasticsearch 7.2, calendar and fixed intervals are separate parameters.
+func (c *Client) dateHistogram(field string, interval DateHistogramInterval) map[string]interface{} {
+	result := map[string]interface{}{
+		"field": field,
+	}
+	switch {
+	case !c.Version().AtLeast(7, 2):
+		result["interval"] = string(interval)
+	case interval == DateHistogramIntervalSecond:
+		result["fixed_interval"] = "1s"
+	default:
+		result["calendar_interval"] = string(interval)
+	}
+	return result
+}
+
 func (c *Client) DateHistogramAggregate(index, doctype string, query map[string]interface{}, field string, interval DateHistogramInterval, buckets int) ([]*Bucket, error) {
+	return c.DateHistogramAggregateContext(context.Background(), index, doctype, query, field, interval, buckets)
+}
+
+// DateHistogramAggregateContext is like DateHistogramAggregate, but aborts the request when ctx is done.
+func (c *Client) DateHistogramAggregateContext(ctx context.Context, index, doctype string, query map[string]interface{}, field string, interval DateHistogramInterval, buckets int) ([]*Bucket, error) {
+	ctx, span := c.startOperation(ctx, APIAggregation, "DateHistogramAggregate", index, doctype)
+	defer span.End()
 	var dateHistogramResult []*Bucket
 	var request map[string]interface{}
 	if interval == DateHistogramIntervalAuto {
@@ -275,10 +331,7 @@ func (c *Client) DateHistogramAggregate(index, doctype string, query map[string]
 			"size": 0,
 			"aggs": map[string]interface{}{
 				"my_datehistogram": map[string]interface{}{
-					"date_histogram": map[string]interface{}{
-						"field":    field,
-						"interval": string(interval),
-					},
+					"date_histogram": c.dateHistogram(field, interval),
 				},
 			},
 		}
@@ -288,12 +341,12 @@ func (c *Client) DateHistogramAggregate(index, doctype string, query map[string]
 	}
 	b, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("could not marshal request: %s", err)
+		return nil, fmt.Errorf("could not marshal request: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_search"
-	res, err := c.post(apipath, b)
+	apipath := c.indexPath(index, doctype) + "/_search"
+	res, err := c.post(ctx, apipath, b)
 	if err != nil {
-		return nil, fmt.Errorf("could not get aggregations: %s", err)
+		return nil, fmt.Errorf("could not get aggregations: %w", err)
 	}
 	result := struct {
 		Aggregations struct {
@@ -308,7 +361,7 @@ func (c *Client) DateHistogramAggregate(index, doctype string, query map[string]
 	decoder := json.NewDecoder(bytes.NewReader(res))
 	decoder.UseNumber()
 	if err := decoder.Decode(&result); err != nil {
-		return nil, fmt.Errorf("could not decode result: %s", err)
+		return nil, fmt.Errorf("could not decode result: %w", err)
 	}
 	for _, bucket := range result.Aggregations.MyDateHistogram.DateHistogram {
 		dateHistogramResult = append(dateHistogramResult, &Bucket{Key: bucket.Key, Count: bucket.Count})
@@ -1,6 +1,7 @@
 package elasticsearch
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -10,7 +11,7 @@ var aggregateClient *Client
 
 func init() {
 	var err error
-	aggregateClient, err = Open("http://localhost:9200")
+	aggregateClient, err = Open(testServer.URL)
 	if err != nil {
 		panic(err)
 	}
@@ -44,7 +45,7 @@ func init() {
 			},
 		},
 	})
-	aggregateClient.put("_template/doc", template)
+	aggregateClient.put(context.Background(), "_template/doc", template, true)
 }
 
 func TestClient_TermAggregate(t *testing.T) {
@@ -0,0 +1,58 @@
+package elasticsearch
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+)
+
+// authorization returns the value of the Authorization header for the credentials
+// in config. An empty string is returned, if no credentials are configured.
+func authorization(config Config) (string, error) {
+	var methods int
+	for _, set := range []bool{config.Username != "" || config.Password != "", config.APIKey != "", config.BearerToken != ""} {
+		if set {
+			methods++
+		}
+	}
+	if methods > 1 {
+		return "", errors.New("only one of basic auth, api key and bearer token can be used")
+	}
+	switch {
+	case config.APIKey != "":
+		return "ApiKey " + config.APIKey, nil
+	case config.BearerToken != "":
+		return "Bearer " + config.BearerToken, nil
+	case config.Username != "" || config.Password != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(config.Username+":"+config.Password)), nil
+	}
+	return "", nil
+}
+
+// redactedHeaders contains all headers that must not appear in debug logs.
+var redactedHeaders = []string{"Authorization"}
+
+// dumpRequest returns the request as text for debug logs with all credentials redacted.
+func dumpRequest(r *http.Request) []byte {
+	saved := map[string][]string{}
+	for _, header := range redactedHeaders {
+		if values, ok := r.Header[header]; ok {
+			saved[header] = values
+			r.Header[header] = []string{"[redacted]"}
+		}
+	}
+	// A compressed body is logged uncompressed.
+	compressed := r.Header.Get("Content-Encoding") == "gzip"
+	b, err := httputil.DumpRequest(r, !compressed)
+	for header, values := range saved {
+		r.Header[header] = values
+	}
+	if err != nil {
+		return []byte(err.Error())
+	}
+	if compressed {
+		b = append(b, decompressedBody(r)...)
+	}
+	return b
+}
@@ -0,0 +1,83 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingLogger records all debug messages.
+type recordingLogger struct {
+	messages []string
+}
+
+func (r *recordingLogger) Infof(format string, a ...interface{}) {}
+
+func (r *recordingLogger) Debugf(format string, a ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(format, a...))
+}
+
+func (r *recordingLogger) DebugMode() bool { return true }
+
+func TestClient_Authorization(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	for _, test := range []struct {
+		config   Config
+		expected string
+	}{
+		{Config{}, ""},
+		{Config{Username: "elastic", Password: "secret"}, "Basic ZWxhc3RpYzpzZWNyZXQ="},
+		{Config{APIKey: "VnVhQ2ZHY0JDZGJrUW0tZTVhT3g6dWkybHAyYXhUTm1zeWFrdzl0dk5udw=="}, "ApiKey VnVhQ2ZHY0JDZGJrUW0tZTVhT3g6dWkybHAyYXhUTm1zeWFrdzl0dk5udw=="},
+		{Config{BearerToken: "AAEAAWVsYXN0aWM"}, "Bearer AAEAAWVsYXN0aWM"},
+	} {
+		test.config.URLs = []string{server.URL}
+		client, err := NewClient(test.config)
+		if err != nil {
+			t.Fatalf("could not open client: %s", err)
+		}
+		if err := client.Ping(); err != nil {
+			t.Fatalf("could not ping: %s", err)
+		}
+		if authorization != test.expected {
+			t.Fatalf("expected authorization %q, got: %q", test.expected, authorization)
+		}
+	}
+}
+
+func TestClient_AuthorizationConflict(t *testing.T) {
+	if _, err := NewClient(Config{URLs: []string{"http://localhost:9200"}, Username: "elastic", APIKey: "key"}); err == nil {
+		t.Fatal("expected error for multiple authentication methods")
+	}
+}
+
+func TestClient_AuthorizationRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	recorder := &recordingLogger{}
+	SetLogger(recorder)
+	defer SetLogger(&discard{})
+	client, err := NewClient(Config{URLs: []string{server.URL}, Username: "elastic", Password: "secret"})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	if len(recorder.messages) == 0 {
+		t.Fatal("expected debug messages")
+	}
+	for _, message := range recorder.messages {
+		if strings.Contains(message, "ZWxhc3RpYzpzZWNyZXQ=") {
+			t.Fatalf("credentials not redacted: %s", message)
+		}
+	}
+}
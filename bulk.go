@@ -2,9 +2,9 @@ package elasticsearch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"path"
 )
 
 // InsertDocuments bulk imports multiple documents into a specific index. Use the document id as
@@ -12,6 +12,13 @@ import (
 // If an error for a specific document occurs, the error will be returned in a map with the document id as key.
 // If an error occurs that regards to all documents, this function will return an error.
 func (c *Client) InsertDocuments(index string, doctype string, docs map[string]map[string]interface{}) (map[string]error, error) {
+	return c.InsertDocumentsContext(context.Background(), index, doctype, docs)
+}
+
+// InsertDocumentsContext is like InsertDocuments, but aborts the bulk import when ctx is done.
+func (c *Client) InsertDocumentsContext(ctx context.Context, index string, doctype string, docs map[string]map[string]interface{}) (map[string]error, error) {
+	ctx, span := c.startOperation(ctx, APIBulk, "InsertDocuments", index, doctype)
+	defer span.End()
 	var buf bytes.Buffer
 	encoder := json.NewEncoder(&buf)
 	for id, doc := range docs {
@@ -20,40 +27,39 @@ func (c *Client) InsertDocuments(index string, doctype string, docs map[string]m
 				"_id": id,
 			},
 		}); err != nil {
-			return nil, fmt.Errorf("could not encode document id: %s", err)
+			return nil, fmt.Errorf("could not encode document id: %w", err)
 		}
 		if err := encoder.Encode(doc); err != nil {
-			return nil, fmt.Errorf("could not encode document: %s", err)
+			return nil, fmt.Errorf("could not encode document: %w", err)
 		}
 	}
-	apipath := path.Join(index, doctype) + "/_bulk"
-	res, err := c.put(apipath, buf.Bytes())
+	apipath := c.indexPath(index, doctype) + "/_bulk"
+	res, err := c.put(ctx, apipath, buf.Bytes(), true)
 	if err != nil {
-		return nil, fmt.Errorf("could not bulk import: %s", err)
+		return nil, fmt.Errorf("could not bulk import: %w", err)
 	}
 	bulkResult := struct {
 		Items []struct {
 			Index struct {
-				Id     string                 `json:"_id"`
-				Status int                    `json:"status"`
-				Error  map[string]interface{} `json:"error"`
+				Id     string      `json:"_id"`
+				Status int         `json:"status"`
+				Error  *ErrorCause `json:"error"`
 			} `json:"index"`
 		} `json:"items"`
 	}{}
 	if err := json.Unmarshal(res, &bulkResult); err != nil {
-		return nil, fmt.Errorf("could not unmarshal bulk result: %s", err)
+		return nil, fmt.Errorf("could not unmarshal bulk result: %w", err)
 	}
 	bulkErrors := map[string]error{}
 	for _, item := range bulkResult.Items {
 		if item.Index.Error != nil {
-			b, err := json.Marshal(item.Index.Error)
-			if err != nil {
-				b = []byte(err.Error())
-			}
-			bulkErrors[item.Index.Id] = fmt.Errorf("could not bulk import document: %s", string(b))
+			itemErr := &ElasticsearchError{StatusCode: item.Index.Status}
+			itemErr.fromCause(*item.Index.Error)
+			bulkErrors[item.Index.Id] = fmt.Errorf("could not bulk import document: %w", itemErr)
 		}
 	}
 	if len(bulkErrors) > 0 {
+		c.metrics.AddBulkItemFailures(APIBulk, len(bulkErrors))
 		return bulkErrors, nil
 	}
 	return nil, nil
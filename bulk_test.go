@@ -8,7 +8,7 @@ var bulkClient *Client
 
 func init() {
 	var err error
-	bulkClient, err = Open("http://localhost:9200")
+	bulkClient, err = Open(testServer.URL)
 	if err != nil {
 		panic(err)
 	}
@@ -2,146 +2,438 @@ package elasticsearch
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
-const sleepOnTooManyRequests = time.Second * 10
-
 // Client is the api client for Elasticsearch.
 type Client struct {
-	baseURL *url.URL
+	authorization        string
+	httpClient           *http.Client
+	roundTrip            RoundTripFunc
+	retryPolicy          *RetryPolicy
+	metrics              Metrics
+	tracer               Tracer
+	compressRequestBody  bool
+	compressResponseBody bool
+	pool                 *nodePool
+	versionMu            sync.RWMutex
+	version              Version
+	seeds                []*url.URL
+	sniffTrigger         chan struct{}
+	closed               chan struct{}
+	closeOnce            sync.Once
+	wg                   sync.WaitGroup
+}
+
+// Config contains the settings for a new Client. Only URLs is required,
+// all other fields fall back to sensible defaults.
+type Config struct {
+	// URLs are the base urls of the Elasticsearch nodes, e.g. http://localhost:9200.
+	// Requests are distributed in round robin order over all nodes.
+	URLs []string
+	// ResurrectTimeout is the time a node is skipped after it could not be reached.
+	// The timeout doubles with every consecutive failure of the node. Default: 60 seconds.
+	ResurrectTimeout time.Duration
+	// SniffInterval enables the discovery of all HTTP enabled nodes of the cluster with the
+	// _nodes/http api of the nodes in URLs. The nodes are discovered right after creating the
+	// client and then in this interval. Nodes that left the cluster are removed. Default: disabled.
+	SniffInterval time.Duration
+	// SniffOnFailure enables the discovery of nodes after a node could not be reached. Default: disabled.
+	SniffOnFailure bool
+	// Username and Password are used for HTTP basic authentication.
+	Username string
+	Password string
+	// APIKey is the base64 encoded api key for the Authorization header, as returned
+	// by the create API key api in the field 'encoded'.
+	APIKey string
+	// BearerToken is used for token based authentication, e.g. with a service account token.
+	BearerToken string
+	// CACert contains the PEM encoded certificate authorities used to verify the server
+	// certificates. By default, the system certificate pool is used.
+	CACert []byte
+	// ClientCert and ClientKey contain the PEM encoded certificate and key, that are
+	// presented to the nodes for TLS client authentication.
+	ClientCert []byte
+	ClientKey  []byte
+	// CertificateFingerprint is the hex encoded SHA-256 fingerprint of a certificate
+	// in the chain of the server, usually the http ca certificate printed by Elasticsearch 8
+	// on the first start. If set, the server certificate is trusted when it matches the
+	// fingerprint or is issued by the certificate matching it for the host name of the node;
+	// CACert and the system certificate pool are not used.
+	CertificateFingerprint string
+	// Transport is used to send all requests, e.g. to use a proxy or a custom dialer.
+	// It can not be combined with the TLS settings above. By default, a transport
+	// shared by all clients is used.
+	Transport http.RoundTripper
+	// Timeout limits the time of a request including reading the response. Keep in mind,
+	// that this includes long running requests like snapshots. Default: no timeout.
+	Timeout time.Duration
+	// RetryPolicy defines which failed requests are retried. Default: DefaultRetryPolicy().
+	RetryPolicy *RetryPolicy
+	// CompressRequestBody enables gzip compression of request bodies, which reduces
+	// the traffic of bulk imports a lot.
+	CompressRequestBody bool
+	// CompressResponseBody requests gzip compressed responses. Elasticsearch compresses
+	// responses, if 'http.compression' is enabled, which is the default.
+	CompressResponseBody bool
+	// Interceptors are called for every request in the given order, e.g. to add
+	// custom headers or to record requests and responses.
+	Interceptors []Interceptor
+	// Metrics receives measurements of all requests. By default, all measurements are discarded.
+	Metrics Metrics
+	// Tracer creates spans for all operations and requests. By default, no spans are created.
+	Tracer Tracer
+	// CheckProduct lets NewClient request the cluster info and fail, if the server is not
+	// a supported Elasticsearch, e.g. OpenSearch or a proxy answering for Elasticsearch.
+	// The version of Elasticsearch is detected as well. Default: disabled.
+	CheckProduct bool
 }
 
-// Open creates a new Client instance based on a baseURL.
+// Open creates a new Client instance based on one or more base urls, each
+// pointing to an Elasticsearch node of the same cluster.
 // This function does not test the connection. Use Ping() for connection tests.
-func Open(baseURL string) (*Client, error) {
-	var (
-		client = &Client{}
-		err    error
-	)
-	if client.baseURL, err = url.Parse(baseURL); err != nil {
-		return nil, fmt.Errorf("could not parse url: %s", err)
+func Open(baseURLs ...string) (*Client, error) {
+	return NewClient(Config{URLs: baseURLs})
+}
+
+// NewClient creates a new Client instance based on a Config.
+// This function does not test the connection, unless CheckProduct is enabled.
+// Use Ping() for connection tests.
+func NewClient(config Config) (*Client, error) {
+	if len(config.URLs) == 0 {
+		return nil, errors.New("no url given")
+	}
+	var urls []*url.URL
+	for _, baseURL := range config.URLs {
+		u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse url: %w", err)
+		}
+		urls = append(urls, u)
+	}
+	auth, err := authorization(config)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{
+		authorization:        auth,
+		httpClient:           httpClient,
+		retryPolicy:          config.RetryPolicy.withDefaults(),
+		compressRequestBody:  config.CompressRequestBody,
+		compressResponseBody: config.CompressResponseBody,
+		pool:                 newNodePool(urls, config.ResurrectTimeout),
+		seeds:                urls,
+		closed:               make(chan struct{}),
+	}
+	client.roundTrip = client.chain(config.Interceptors)
+	client.metrics = config.Metrics
+	if client.metrics == nil {
+		client.metrics = discardMetrics{}
+	}
+	client.tracer = config.Tracer
+	if client.tracer == nil {
+		client.tracer = discardTracer{}
+	}
+	if config.CheckProduct {
+		info, err := client.Info()
+		if err != nil {
+			return nil, err
+		}
+		if err := info.CheckProduct(); err != nil {
+			return nil, err
+		}
+	}
+	if config.SniffOnFailure {
+		client.sniffTrigger = make(chan struct{}, 1)
+	}
+	if config.SniffInterval > 0 || config.SniffOnFailure {
+		client.startSniffer(config.SniffInterval)
 	}
 	return client, nil
 }
 
-// Ping is the connection test for the Elasticsearch client.
+// Close stops all background activities of the client, like sniffing nodes.
+// Requests can still be done after closing the client.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.wg.Wait()
+	return nil
+}
+
+// Ping is the connection test for the Elasticsearch client. It also detects the
+// version of Elasticsearch. Until Ping succeeded, the client uses the api paths of
+// Elasticsearch 6 with mapping types. On Elasticsearch 7 and newer, the doctype
+// arguments are ignored and the typeless apis are used.
 func (c *Client) Ping() error {
-	_, err := c.get("", nil)
+	return c.PingContext(context.Background())
+}
+
+// PingContext is like Ping, but aborts the connection test when ctx is done.
+func (c *Client) PingContext(ctx context.Context) error {
+	ctx, span := c.startOperation(ctx, APICluster, "Ping", "", "")
+	defer span.End()
+	res, err := c.get(ctx, "", nil)
 	if err != nil {
-		return fmt.Errorf("could not ping server: %s", err)
+		return fmt.Errorf("could not ping server: %w", err)
+	}
+	// The connection test succeeds even if the version is missing, e.g. behind
+	// a proxy. The api paths for Elasticsearch 6 are used in this case.
+	info := ClusterInfo{}
+	json.Unmarshal(res, &info)
+	if err := c.detectVersion(info); err != nil {
+		log.Infof("%s", err)
 	}
 	return nil
 }
 
-func (c *Client) do(r *http.Request) ([]byte, bool, error) {
-	if log.DebugMode() {
-		b, err := httputil.DumpRequest(r, true)
+// connectionError is returned by do, if the node could not be reached at all.
+type connectionError struct {
+	err error
+}
+
+func (e *connectionError) Error() string {
+	return fmt.Sprintf("could not do request: %s", e.err)
+}
+
+func (e *connectionError) Unwrap() error {
+	return e.err
+}
+
+// sent returns false, if the request was never written to the connection and
+// can be retried on another node without side effects.
+func (e *connectionError) sent() bool {
+	var opErr *net.OpError
+	return !errors.As(e.err, &opErr) || opErr.Op != "dial"
+}
+
+// timeout returns true, if the node was reached, but did not answer within the
+// client timeout. The node is not dead in this case, the request is just slow.
+func (e *connectionError) timeout() bool {
+	var urlErr *url.Error
+	return errors.As(e.err, &urlErr) && urlErr.Timeout() && e.sent()
+}
+
+// perform sends the request to the next node of the pool and retries it according
+// to the retry policy. Each retry sends exactly the same request. If a node can
+// not be reached, it is marked as dead and the retry is sent to the next node
+// without waiting. A node exceeding the client timeout is not marked as dead.
+// Idempotent requests can be sent multiple times with the same effect as sending
+// them once, so they are retried, even if they may have been executed.
+func (c *Client) perform(ctx context.Context, method, apipath string, body []byte, idempotent bool) (result *response, err error) {
+	op := operationFromContext(ctx)
+	ctx, span := c.startRequestSpan(ctx, method)
+	defer func() {
 		if err != nil {
-			b = []byte(err.Error())
+			span.RecordError(err)
+			op.span.RecordError(err)
+		}
+		span.End()
+	}()
+	compressed := c.compressRequestBody && len(body) > 0
+	if compressed {
+		if body, err = compress(body); err != nil {
+			return nil, err
 		}
-		log.Debugf("Elasticsearch Request: %s", string(b))
-	}
-	client := &http.Client{}
-	resp, err := client.Do(r)
-	if err != nil {
-		return nil, false, fmt.Errorf("could not do request: %s", err)
 	}
-	defer resp.Body.Close()
-	if log.DebugMode() {
-		b, err := httputil.DumpResponse(resp, true)
+	span.SetAttribute(AttributeRequestBodyLength, len(body))
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			c.metrics.IncRetries(op.api)
+			span.SetAttribute(AttributeRetries, attempt-1)
+		}
+		n := c.pool.next()
+		req, err := c.newRequest(ctx, method, fmt.Sprintf("%s/%s", n.url.String(), apipath), body)
 		if err != nil {
-			b = []byte(err.Error())
+			return nil, err
 		}
-		log.Debugf("Elasticsearch Response: %s", string(b))
-	}
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		if resp.StatusCode == http.StatusTooManyRequests {
-			return nil, true, nil
+		if compressed {
+			req.Header.Set("Content-Encoding", "gzip")
 		}
-		if body, _ := ioutil.ReadAll(resp.Body); body != nil {
-			return nil, false, fmt.Errorf("http status %d (%s)", resp.StatusCode, string(body))
+		if traceParent := span.TraceParent(); traceParent != "" {
+			req.Header.Set("traceparent", traceParent)
+		}
+		start := time.Now()
+		res, err := c.do(req)
+		if err != nil {
+			c.metrics.ObserveRequest(op.api, method, 0, time.Since(start))
+			var connErr *connectionError
+			if !errors.As(err, &connErr) || ctx.Err() != nil {
+				return nil, err
+			}
+			if connErr.timeout() {
+				// Only reads are retried, a write may still be running on the node.
+				if attempt >= c.retryPolicy.MaxAttempts || !idempotent || (method != http.MethodGet && method != http.MethodHead) {
+					return nil, err
+				}
+				log.Infof("retrying %s request to %s: %s", method, apipath, err)
+				continue
+			}
+			c.pool.markDead(n)
+			c.triggerSniff()
+			if attempt >= c.retryPolicy.MaxAttempts || (connErr.sent() && !c.retryPolicy.retryRequest(idempotent)) {
+				return nil, err
+			}
+			log.Infof("retrying %s request to %s: %s", method, apipath, err)
+			continue
+		}
+		c.metrics.ObserveRequest(op.api, method, res.statusCode, time.Since(start))
+		span.SetAttribute(AttributeHTTPStatusCode, res.statusCode)
+		span.SetAttribute(AttributeResponseBodyLength, len(res.body))
+		if res.statusCode == http.StatusTooManyRequests {
+			c.metrics.IncThrottled(op.api)
+		}
+		c.pool.markAlive(n)
+		if res.statusCode == http.StatusOK || res.statusCode == http.StatusCreated {
+			return res, nil
+		}
+		if method == http.MethodHead && res.statusCode == http.StatusNotFound {
+			// the answer for missing resources, not an error
+			return res, nil
+		}
+		err = newElasticsearchError(res.statusCode, res.body)
+		if attempt >= c.retryPolicy.MaxAttempts || !c.retryPolicy.retryStatus(idempotent, res.statusCode) {
+			return nil, err
+		}
+		wait := c.retryPolicy.backoff(attempt, res.header)
+		log.Infof("retrying %s request to %s in %s: %s", method, apipath, wait, err)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
 		}
-		return nil, false, fmt.Errorf("http status %d", resp.StatusCode)
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, false, fmt.Errorf("could not read response body: %s", err)
 	}
-	return body, false, nil
 }
 
-func (c *Client) post(apipath string, json []byte) ([]byte, error) {
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s", c.baseURL.String(), apipath), bytes.NewReader(json))
+// newRequest prepares a request with all headers required by Elasticsearch.
+func (c *Client) newRequest(ctx context.Context, method, rawurl string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawurl, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("could not prepare post request: %s", err)
+		return nil, fmt.Errorf("could not prepare %s request: %w", strings.ToLower(method), err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	b, retry, err := c.do(req)
-	if err != nil {
-		return nil, err
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
 	}
-	if retry == true {
-		time.Sleep(sleepOnTooManyRequests)
-		return c.get(apipath, json)
+	if c.compressResponseBody {
+		req.Header.Set("Accept-Encoding", "gzip")
 	}
-	return b, nil
+	return req, nil
 }
 
-func (c *Client) get(apipath string, json []byte) ([]byte, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", c.baseURL.String(), apipath), bytes.NewReader(json))
-	if err != nil {
-		return nil, fmt.Errorf("could not prepare get request: %s", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	b, retry, err := c.do(req)
+// response is a completely read http response.
+type response struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// do sends a single request through the interceptors and reads the response.
+// Only connection errors are returned as error, the status code has to be
+// checked by the caller.
+func (c *Client) do(r *http.Request) (*response, error) {
+	resp, err := c.roundTrip(r)
 	if err != nil {
 		return nil, err
 	}
-	if retry == true {
-		time.Sleep(sleepOnTooManyRequests)
-		return c.get(apipath, json)
+	if resp.Body == nil {
+		resp.Body = http.NoBody
 	}
-	return b, nil
-}
-
-func (c *Client) put(apipath string, json []byte) ([]byte, error) {
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/%s", c.baseURL.String(), apipath), bytes.NewReader(json))
-	if err != nil {
-		return nil, fmt.Errorf("could not prepare put request: %s", err)
+	defer func(body io.ReadCloser) {
+		// drain the body, so that the connection can be reused
+		io.Copy(ioutil.Discard, body)
+		body.Close()
+	}(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := decompress(resp.Body)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if err == nil {
+			defer gz.Close()
+			resp.Body = gz
+			resp.Header.Del("Content-Encoding")
+			resp.ContentLength = -1
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
-	b, retry, err := c.do(req)
-	if err != nil {
-		return nil, err
+	if log.DebugMode() {
+		b, err := httputil.DumpResponse(resp, true)
+		if err != nil {
+			b = []byte(err.Error())
+		}
+		log.Debugf("Elasticsearch Response: %s", string(b))
 	}
-	if retry == true {
-		time.Sleep(sleepOnTooManyRequests)
-		return c.get(apipath, json)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response body: %w", err)
 	}
-	return b, nil
+	return &response{
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		body:       body,
+	}, nil
+}
+
+func (c *Client) post(ctx context.Context, apipath string, json []byte) ([]byte, error) {
+	return responseBody(c.perform(ctx, "POST", apipath, json, false))
+}
+
+func (c *Client) get(ctx context.Context, apipath string, json []byte) ([]byte, error) {
+	return responseBody(c.perform(ctx, "GET", apipath, json, true))
+}
+
+// put sends a PUT request. It is not idempotent, if it only succeeds once, e.g.
+// because it creates a resource or has a concurrency condition.
+func (c *Client) put(ctx context.Context, apipath string, json []byte, idempotent bool) ([]byte, error) {
+	return responseBody(c.perform(ctx, "PUT", apipath, json, idempotent))
+}
+
+// delete_ sends a DELETE request. Like put, it is not idempotent with a concurrency condition.
+func (c *Client) delete_(ctx context.Context, apipath string, json []byte, idempotent bool) ([]byte, error) {
+	return responseBody(c.perform(ctx, "DELETE", apipath, json, idempotent))
 }
 
-func (c *Client) delete_(apipath string, json []byte) ([]byte, error) {
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/%s", c.baseURL.String(), apipath), bytes.NewReader(json))
+// head sends a HEAD request and returns true, if the resource exists.
+func (c *Client) head(ctx context.Context, apipath string) (bool, error) {
+	res, err := c.perform(ctx, http.MethodHead, apipath, nil, true)
 	if err != nil {
-		return nil, fmt.Errorf("could not prepare delete request: %s", err)
+		return false, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	b, retry, err := c.do(req)
+	return res.statusCode != http.StatusNotFound, nil
+}
+
+// responseBody returns the body of the response returned by perform.
+func responseBody(res *response, err error) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if retry == true {
-		time.Sleep(sleepOnTooManyRequests)
-		return c.get(apipath, json)
+	return res.body, nil
+}
+
+// sleep pauses the current goroutine for the duration d or until ctx is done,
+// whichever happens first. If ctx is done before d elapsed, ctx.Err() is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return b, nil
 }
 
 // Refresh parameter for most requests, default should be RefreshFalse,
@@ -0,0 +1,99 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NextronSystems/go-elasticsearch/estest"
+)
+
+// testServer is the fake Elasticsearch for the tests of the apis.
+var testServer = estest.NewServer()
+
+// fakeVersions are the versions of the fake, that tests with version specific
+// behaviour run against by default.
+var fakeVersions = []string{"6.8.23", "7.17.0"}
+
+// forEachVersion runs test as a subtest for each version with a client connected
+// to a new fake of Elasticsearch in that version.
+func forEachVersion(t *testing.T, versions []string, test func(t *testing.T, server *estest.Server, client *Client)) {
+	for _, version := range versions {
+		t.Run(version, func(t *testing.T) {
+			server := estest.NewServerVersion(version)
+			t.Cleanup(server.Close)
+			client, err := Open(server.URL)
+			if err != nil {
+				t.Fatalf("could not open client: %s", err)
+			}
+			if err := client.Ping(); err != nil {
+				t.Fatalf("could not ping: %s", err)
+			}
+			test(t, server, client)
+		})
+	}
+}
+
+func TestClient_PingContextTooManyRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = client.PingContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > DefaultRetryPolicy().InitialBackoff/2 {
+		t.Fatalf("ping was not aborted while sleeping, took %s", elapsed)
+	}
+}
+
+func TestClient_ScrollDocumentsContextCanceled(t *testing.T) {
+	cleared := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			b, _ := ioutil.ReadAll(r.Body)
+			cleared <- string(b)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"_scroll_id":"1","hits":{"hits":[{"_id":"1"},{"_id":"2"}]}}`))
+	}))
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	docs := make(chan map[string]interface{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- client.ScrollDocumentsContext(ctx, "testclient_scrolldocumentscontext", "doc", nil, docs)
+	}()
+	<-docs
+	cancel()
+	for range docs {
+	}
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("expected context canceled, got: %v", err)
+	}
+	select {
+	case body := <-cleared:
+		if body != `{"scroll_id":"1"}` {
+			t.Fatalf("unexpected clear scroll request: %s", body)
+		}
+	default:
+		t.Fatal("expected the scroll to be cleared")
+	}
+}
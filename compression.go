@@ -0,0 +1,66 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// gzipWriters reuses gzip writers, because their allocation is expensive
+// compared to compressing a small request.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(ioutil.Discard)
+	},
+}
+
+// compress returns the gzip compressed body.
+func compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, fmt.Errorf("could not compress body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("could not compress body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns a reader for the gzip compressed body. The caller
+// has to close the returned reader.
+func decompress(body io.Reader) (io.ReadCloser, error) {
+	r, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress body: %w", err)
+	}
+	return r, nil
+}
+
+// decompressedBody returns the uncompressed body of a compressed request for debug logs.
+func decompressedBody(r *http.Request) []byte {
+	if r.GetBody == nil {
+		return []byte("[compressed body]")
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return []byte(err.Error())
+	}
+	defer body.Close()
+	gz, err := decompress(body)
+	if err != nil {
+		return []byte(err.Error())
+	}
+	defer gz.Close()
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return b
+}
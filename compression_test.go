@@ -0,0 +1,168 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// gzipServer returns a test server that accepts gzip compressed requests
+// and counts the received body bytes.
+func gzipServer(t testing.TB, received *int64, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter := &countingReader{r: r.Body}
+		var body io.Reader = counter
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(counter)
+			if err != nil {
+				t.Errorf("could not decompress request: %s", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			t.Errorf("could not read request: %s", err)
+		}
+		atomic.AddInt64(received, counter.n)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestClient_Compression(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("could not decompress request: %s", err)
+			return
+		}
+		body, _ := ioutil.ReadAll(gz)
+		requests = append(requests, r.Header.Get("Content-Encoding")+" "+r.Header.Get("Accept-Encoding")+" "+string(body))
+		w.Header().Set("Content-Encoding", "gzip")
+		gzw := gzip.NewWriter(w)
+		gzw.Write([]byte(`{"hits":{"total":1,"hits":[{"_id":"1","_source":{"field1":"value1"}}]}}`))
+		gzw.Close()
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{
+		URLs:                 []string{server.URL},
+		CompressRequestBody:  true,
+		CompressResponseBody: true,
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	docs, total, err := client.GetDocuments("testclient_compression", "doc", map[string]interface{}{"match_all": map[string]interface{}{}}, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("could not get documents: %s", err)
+	}
+	if total != 1 || len(docs) != 1 || docs[0]["_id"] != "1" {
+		t.Fatalf("unexpected documents: %d %v", total, docs)
+	}
+	if len(requests) != 1 || requests[0] != `gzip gzip {"query":{"match_all":{}}}` {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
+
+func TestClient_CompressionDebugLog(t *testing.T) {
+	server := gzipServer(t, new(int64), `{}`)
+	defer server.Close()
+	recorder := &recordingLogger{}
+	SetLogger(recorder)
+	defer SetLogger(&discard{})
+	client, err := NewClient(Config{URLs: []string{server.URL}, CompressRequestBody: true})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.InsertDocument("testclient_compression", "doc", "1", map[string]interface{}{"field": "logged"}, RefreshFalse); err != nil {
+		t.Fatalf("could not insert document: %s", err)
+	}
+	var logged bool
+	for _, message := range recorder.messages {
+		if strings.Contains(message, "\x1f\x8b") {
+			t.Fatalf("compressed body in debug log: %q", message)
+		}
+		logged = logged || strings.Contains(message, `{"field":"logged"}`)
+	}
+	if !logged {
+		t.Fatalf("expected uncompressed body in debug log: %v", recorder.messages)
+	}
+}
+
+// bulkDocuments returns n documents with some typical fields.
+func bulkDocuments(n int) map[string]map[string]interface{} {
+	docs := map[string]map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		docs[fmt.Sprint(i)] = map[string]interface{}{
+			"timestamp": "2018-11-30T10:17:00Z",
+			"hostname":  fmt.Sprintf("host-%d.example.com", i%20),
+			"message":   strings.Repeat("lorem ipsum dolor sit amet ", 4),
+			"level":     "info",
+			"count":     i,
+		}
+	}
+	return docs
+}
+
+func benchmarkInsertDocuments(b *testing.B, compress bool) {
+	var received int64
+	server := gzipServer(b, &received, `{"items":[]}`)
+	defer server.Close()
+	client, err := NewClient(Config{URLs: []string{server.URL}, CompressRequestBody: compress})
+	if err != nil {
+		b.Fatalf("could not open client: %s", err)
+	}
+	docs := bulkDocuments(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.InsertDocuments("benchmark", "doc", docs); err != nil {
+			b.Fatalf("could not insert documents: %s", err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&received))/float64(b.N), "sent-bytes/op")
+}
+
+func BenchmarkInsertDocuments(b *testing.B) {
+	b.Run("uncompressed", func(b *testing.B) { benchmarkInsertDocuments(b, false) })
+	b.Run("gzip", func(b *testing.B) { benchmarkInsertDocuments(b, true) })
+}
+
+func TestCompress(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"index":{"_id":"1"}}`+"\n"), 100)
+	compressed, err := compress(body)
+	if err != nil {
+		t.Fatalf("could not compress: %s", err)
+	}
+	if len(compressed) >= len(body) {
+		t.Fatalf("expected compressed body to be smaller, got %d >= %d bytes", len(compressed), len(body))
+	}
+	r, err := decompress(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("could not decompress: %s", err)
+	}
+	defer r.Close()
+	decompressed, _ := ioutil.ReadAll(r)
+	if !bytes.Equal(decompressed, body) {
+		t.Fatal("decompressed body differs")
+	}
+}
@@ -2,9 +2,10 @@ package elasticsearch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"path"
+	"time"
 )
 
 // Order can be used to define the order of the Elasticsearch result.
@@ -27,29 +28,34 @@ func (o *Order) MarshalJSON() ([]byte, error) {
 // If multiple inserts are done and all changes have to be done before continuing,
 // set refresh to false and call Refresh() after.
 func (c *Client) InsertDocument(index, doctype, id string, document map[string]interface{}, refresh Refresh) error {
-	b, err := json.Marshal(document)
-	if err != nil {
-		return fmt.Errorf("could not marshal the document: %s", err)
-	}
-	apipath := path.Join(index, doctype, id) + "?refresh=" + getRefreshString(refresh)
-	if _, err := c.put(apipath, b); err != nil {
-		return fmt.Errorf("could not insert document: %s", err)
-	}
-	return nil
+	return c.InsertDocumentContext(context.Background(), index, doctype, id, document, refresh)
+}
+
+// InsertDocumentContext is like InsertDocument, but aborts the request when ctx is done.
+func (c *Client) InsertDocumentContext(ctx context.Context, index, doctype, id string, document map[string]interface{}, refresh Refresh) error {
+	_, err := c.InsertDocumentWithOptionsContext(ctx, index, doctype, id, document, WriteOptions{Refresh: refresh})
+	return err
 }
 
 // GetDocument returns the document in a specific index and a specific id.
 func (c *Client) GetDocument(index, doctype, id string) (map[string]interface{}, error) {
-	apipath := path.Join(index, doctype, id)
-	b, err := c.get(apipath, nil)
+	return c.GetDocumentContext(context.Background(), index, doctype, id)
+}
+
+// GetDocumentContext is like GetDocument, but aborts the request when ctx is done.
+func (c *Client) GetDocumentContext(ctx context.Context, index, doctype, id string) (map[string]interface{}, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "GetDocument", index, doctype)
+	defer span.End()
+	apipath := c.documentPath(index, doctype, id)
+	b, err := c.get(ctx, apipath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not get document: %s", err)
+		return nil, fmt.Errorf("could not get document: %w", err)
 	}
 	decoder := json.NewDecoder(bytes.NewReader(b))
 	decoder.UseNumber()
 	result := map[string]interface{}{}
 	if err := decoder.Decode(&result); err != nil {
-		return nil, fmt.Errorf("could not decode document: %s", err)
+		return nil, fmt.Errorf("could not decode document: %w", err)
 	}
 	return result, nil
 }
@@ -58,6 +64,13 @@ func (c *Client) GetDocument(index, doctype, id string) (map[string]interface{},
 // A offset and size have to be defined. The offset+size have to be lower than 10.000, otherwise
 // Elasticsearch returns an error. If you want to get more than 10.000, use ScrollDocuments instead.
 func (c *Client) GetDocuments(index, doctype string, query map[string]interface{}, from int64, size int64, order *Order) ([]map[string]interface{}, int64, error) {
+	return c.GetDocumentsContext(context.Background(), index, doctype, query, from, size, order)
+}
+
+// GetDocumentsContext is like GetDocuments, but aborts the search when ctx is done.
+func (c *Client) GetDocumentsContext(ctx context.Context, index, doctype string, query map[string]interface{}, from int64, size int64, order *Order) ([]map[string]interface{}, int64, error) {
+	ctx, span := c.startOperation(ctx, APISearch, "GetDocuments", index, doctype)
+	defer span.End()
 	request := map[string]interface{}{}
 	if query != nil {
 		request["query"] = query
@@ -67,12 +80,16 @@ func (c *Client) GetDocuments(index, doctype string, query map[string]interface{
 	}
 	b, err := json.Marshal(request)
 	if err != nil {
-		return nil, 0, fmt.Errorf("could not marshal query: %s", err)
+		return nil, 0, fmt.Errorf("could not marshal query: %w", err)
+	}
+	apipath := c.indexPath(index, doctype) + fmt.Sprintf("/_search?from=%d&size=%d", from, size)
+	if c.typeless() {
+		// Elasticsearch 7 returns the total as object by default
+		apipath += "&rest_total_hits_as_int=true"
 	}
-	apipath := path.Join(index, doctype) + fmt.Sprintf("/_search?from=%d&size=%d", from, size)
-	b, err = c.get(apipath, b)
+	b, err = c.get(ctx, apipath, b)
 	if err != nil {
-		return nil, 0, fmt.Errorf("could not get documents: %s", err)
+		return nil, 0, fmt.Errorf("could not get documents: %w", err)
 	}
 	decoder := json.NewDecoder(bytes.NewReader(b))
 	decoder.UseNumber()
@@ -83,7 +100,7 @@ func (c *Client) GetDocuments(index, doctype string, query map[string]interface{
 		} `json:"hits"`
 	}{}
 	if err := decoder.Decode(&result); err != nil {
-		return nil, 0, fmt.Errorf("could not decode documents: %s", err)
+		return nil, 0, fmt.Errorf("could not decode documents: %w", err)
 	}
 	return result.Hits.Hits, result.Hits.Total, nil
 }
@@ -93,24 +110,13 @@ func (c *Client) GetDocuments(index, doctype string, query map[string]interface{
 // Then elasticsearch has to compile the script only once. Elasticsearch will also return
 // an error, if to many different scripts are executed in a small time interval.
 func (c *Client) UpdateDocument(index, doctype, id string, painlessScript string, params map[string]interface{}, refresh Refresh) error {
-	script := map[string]interface{}{
-		"source": painlessScript,
-		"lang":   "painless",
-	}
-	if params != nil {
-		script["params"] = params
-	}
-	b, err := json.Marshal(map[string]interface{}{
-		"script": script,
-	})
-	if err != nil {
-		return fmt.Errorf("could not marshal the changes: %s", err)
-	}
-	apipath := path.Join(index, doctype, id) + "/_update?refresh=" + getRefreshString(refresh)
-	if _, err := c.post(apipath, b); err != nil {
-		return fmt.Errorf("could not update document: %s", err)
-	}
-	return nil
+	return c.UpdateDocumentContext(context.Background(), index, doctype, id, painlessScript, params, refresh)
+}
+
+// UpdateDocumentContext is like UpdateDocument, but aborts the request when ctx is done.
+func (c *Client) UpdateDocumentContext(ctx context.Context, index, doctype, id string, painlessScript string, params map[string]interface{}, refresh Refresh) error {
+	_, err := c.UpdateDocumentWithOptionsContext(ctx, index, doctype, id, painlessScript, params, WriteOptions{Refresh: refresh})
+	return err
 }
 
 // UpdateDocuments runs an update script on multiple documents in a specific index. A query is optional.
@@ -118,6 +124,13 @@ func (c *Client) UpdateDocument(index, doctype, id string, painlessScript string
 // Then elasticsearch has to compile the script only once. Elasticsearch will also return
 // an error, if to many different scripts are executed in a small time interval.
 func (c *Client) UpdateDocuments(index, doctype string, query map[string]interface{}, painlessScript string, params map[string]interface{}, refresh Refresh) error {
+	return c.UpdateDocumentsContext(context.Background(), index, doctype, query, painlessScript, params, refresh)
+}
+
+// UpdateDocumentsContext is like UpdateDocuments, but aborts the update by query when ctx is done.
+func (c *Client) UpdateDocumentsContext(ctx context.Context, index, doctype string, query map[string]interface{}, painlessScript string, params map[string]interface{}, refresh Refresh) error {
+	ctx, span := c.startOperation(ctx, APIDocument, "UpdateDocuments", index, doctype)
+	defer span.End()
 	script := map[string]interface{}{
 		"source": painlessScript,
 		"lang":   "painless",
@@ -130,35 +143,44 @@ func (c *Client) UpdateDocuments(index, doctype string, query map[string]interfa
 		"script": script,
 	})
 	if err != nil {
-		return fmt.Errorf("could not marshal the query: %s", err)
+		return fmt.Errorf("could not marshal the query: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_update_by_query?conflicts=proceed&refresh=" + getRefreshString(refresh)
-	if _, err := c.post(apipath, b); err != nil {
-		return fmt.Errorf("could not update documents: %s", err)
+	apipath := c.indexPath(index, doctype) + "/_update_by_query?conflicts=proceed&refresh=" + getRefreshString(refresh)
+	if _, err := c.post(ctx, apipath, b); err != nil {
+		return fmt.Errorf("could not update documents: %w", err)
 	}
 	return nil
 }
 
 // DeleteDocument deletes a specific document in a specific index.
 func (c *Client) DeleteDocument(index, doctype, id string, refresh Refresh) error {
-	apipath := path.Join(index, doctype, id) + "?refresh=" + getRefreshString(refresh)
-	if _, err := c.delete_(apipath, nil); err != nil {
-		return fmt.Errorf("could not update document: %s", err)
-	}
-	return nil
+	return c.DeleteDocumentContext(context.Background(), index, doctype, id, refresh)
+}
+
+// DeleteDocumentContext is like DeleteDocument, but aborts the request when ctx is done.
+func (c *Client) DeleteDocumentContext(ctx context.Context, index, doctype, id string, refresh Refresh) error {
+	_, err := c.DeleteDocumentWithOptionsContext(ctx, index, doctype, id, WriteOptions{Refresh: refresh})
+	return err
 }
 
 // DeleteDocuments deletes multiple documents in a specific index. A query is optional.
 func (c *Client) DeleteDocuments(index, doctype string, query map[string]interface{}, refresh Refresh) error {
+	return c.DeleteDocumentsContext(context.Background(), index, doctype, query, refresh)
+}
+
+// DeleteDocumentsContext is like DeleteDocuments, but aborts the delete by query when ctx is done.
+func (c *Client) DeleteDocumentsContext(ctx context.Context, index, doctype string, query map[string]interface{}, refresh Refresh) error {
+	ctx, span := c.startOperation(ctx, APIDocument, "DeleteDocuments", index, doctype)
+	defer span.End()
 	b, err := json.Marshal(map[string]interface{}{
 		"query": query,
 	})
 	if err != nil {
-		return fmt.Errorf("could not marshal the query: %s", err)
+		return fmt.Errorf("could not marshal the query: %w", err)
 	}
-	apipath := path.Join(index, doctype) + "/_delete_by_query?refresh=" + getRefreshString(refresh)
-	if _, err := c.post(apipath, b); err != nil {
-		return fmt.Errorf("could not delete by query: %s", err)
+	apipath := c.indexPath(index, doctype) + "/_delete_by_query?refresh=" + getRefreshString(refresh)
+	if _, err := c.post(ctx, apipath, b); err != nil {
+		return fmt.Errorf("could not delete by query: %w", err)
 	}
 	return nil
 }
@@ -167,8 +189,16 @@ func (c *Client) DeleteDocuments(index, doctype string, query map[string]interfa
 // This function will return always all found documents without an order into the 'docs' channel. Ensure that this function
 // is called as a go routine!
 func (c *Client) ScrollDocuments(index, doctype string, query map[string]interface{}, docs chan map[string]interface{}) error {
+	return c.ScrollDocumentsContext(context.Background(), index, doctype, query, docs)
+}
+
+// ScrollDocumentsContext is like ScrollDocuments, but stops scrolling when ctx is done. In this case,
+// ctx.Err() is returned and the 'docs' channel is closed, even if not all documents were sent.
+func (c *Client) ScrollDocumentsContext(ctx context.Context, index, doctype string, query map[string]interface{}, docs chan map[string]interface{}) error {
+	ctx, span := c.startOperation(ctx, APIScroll, "ScrollDocuments", index, doctype)
+	defer span.End()
 	defer close(docs)
-	apipath := path.Join(index, doctype) + "/_search?scroll=5m"
+	apipath := c.indexPath(index, doctype) + "/_search?scroll=5m"
 	req := map[string]interface{}{
 		"size": 1000,
 		"sort": []string{"_doc"},
@@ -176,10 +206,10 @@ func (c *Client) ScrollDocuments(index, doctype string, query map[string]interfa
 	if query != nil {
 		req["query"] = query
 	}
-	return c.scrollDocuments(apipath, req, docs, "")
+	return c.scrollDocuments(ctx, apipath, req, docs, "")
 }
 
-func (c *Client) scrollDocuments(apipath string, req map[string]interface{}, docs chan map[string]interface{}, scrollId string) error {
+func (c *Client) scrollDocuments(ctx context.Context, apipath string, req map[string]interface{}, docs chan map[string]interface{}, scrollId string) error {
 	scrollResult := struct {
 		ScrollId string `json:"_scroll_id"`
 		Hits     struct {
@@ -188,43 +218,71 @@ func (c *Client) scrollDocuments(apipath string, req map[string]interface{}, doc
 	}{}
 	b, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("could not marshal scroll request: %s", err)
+		return fmt.Errorf("could not marshal scroll request: %w", err)
 	}
-	res, err := c.post(apipath, b)
+	res, err := c.post(ctx, apipath, b)
 	if err != nil {
-		return fmt.Errorf("could not scroll documents: %s", err)
+		if scrollId != "" && ctx.Err() != nil {
+			c.clearScroll(ctx, scrollId)
+		}
+		return fmt.Errorf("could not scroll documents: %w", err)
 	}
 	decoder := json.NewDecoder(bytes.NewReader(res))
 	decoder.UseNumber()
 	if err := decoder.Decode(&scrollResult); err != nil {
-		return fmt.Errorf("could not unmarshal scroll result: %s", err)
+		return fmt.Errorf("could not unmarshal scroll result: %w", err)
 	}
 	if scrollId != "" && scrollId != scrollResult.ScrollId {
-		if err := c.deleteScroll(scrollId); err != nil {
-			return fmt.Errorf("could not delete scroll: %s", err)
+		if err := c.deleteScroll(ctx, scrollId); err != nil {
+			return fmt.Errorf("could not delete scroll: %w", err)
 		}
 	}
 	if len(scrollResult.Hits.Hits) == 0 {
 		return nil
 	}
 	for _, hit := range scrollResult.Hits.Hits {
-		docs <- hit
+		select {
+		case docs <- hit:
+		case <-ctx.Done():
+			c.clearScroll(ctx, scrollResult.ScrollId)
+			return ctx.Err()
+		}
 	}
-	return c.scrollDocuments("_search/scroll", map[string]interface{}{
+	return c.scrollDocuments(ctx, "_search/scroll", map[string]interface{}{
 		"scroll":    "5m",
 		"scroll_id": scrollResult.ScrollId,
 	}, docs, scrollResult.ScrollId)
 }
 
-func (c *Client) deleteScroll(scrollId string) error {
+// clearScrollTimeout limits the time to clear a scroll after its operation was canceled.
+const clearScrollTimeout = 10 * time.Second
+
+// detachedContext keeps the values of a context, but is not canceled with it.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// clearScroll deletes the scroll, even if ctx is already done. Otherwise Elasticsearch
+// keeps the scroll context open until the keep alive expires.
+func (c *Client) clearScroll(ctx context.Context, scrollId string) error {
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, clearScrollTimeout)
+	defer cancel()
+	return c.deleteScroll(ctx, scrollId)
+}
+
+func (c *Client) deleteScroll(ctx context.Context, scrollId string) error {
 	b, err := json.Marshal(map[string]interface{}{
 		"scroll_id": scrollId,
 	})
 	if err != nil {
-		return fmt.Errorf("could not marshal the delete scroll query: %s", err)
+		return fmt.Errorf("could not marshal the delete scroll query: %w", err)
 	}
-	if _, err := c.delete_("_search/scroll", b); err != nil {
-		return fmt.Errorf("could not delete the scroll: %s", err)
+	if _, err := c.delete_(ctx, "_search/scroll", b, true); err != nil {
+		return fmt.Errorf("could not delete the scroll: %w", err)
 	}
 	return nil
 }
@@ -2,7 +2,6 @@ package elasticsearch
 
 import (
 	"fmt"
-	"strings"
 	"sync"
 	"testing"
 )
@@ -11,7 +10,7 @@ var documentClient *Client
 
 func init() {
 	var err error
-	documentClient, err = Open("http://localhost:9200")
+	documentClient, err = Open(testServer.URL)
 	if err != nil {
 		panic(err)
 	}
@@ -48,7 +47,7 @@ func TestClient_InsertGetDeleteDocument(t *testing.T) {
 		t.Fatalf("could not delete document: %s", err)
 	}
 	_, err = documentClient.GetDocument("testclient_insertgetdeletedocument", "doc", "1")
-	if err != nil && strings.Contains(err.Error(), "http status 404") {
+	if IsNotFound(err) {
 		t.Logf("error as expected: %s", err)
 	} else {
 		t.Fatalf("unknown error after deletion: %s", err)
@@ -91,11 +90,11 @@ func TestClient_ScrollDocuments(t *testing.T) {
 	documentClient.Refresh("testclient_scrolldocuments")
 	docs := make(chan map[string]interface{}, 1)
 	wg := sync.WaitGroup{}
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 		if err := documentClient.ScrollDocuments("testclient_scrolldocuments", "doc", nil, docs); err != nil {
-			t.Fatalf("could not scroll documents: %s", err)
+			t.Errorf("could not scroll documents: %s", err)
 		}
 	}()
 	var counter int
@@ -117,11 +116,11 @@ func TestClient_ScrollDocuments2(t *testing.T) {
 	documentClient.Refresh("testclient_scrolldocuments2")
 	docs := make(chan map[string]interface{}, 1)
 	wg := sync.WaitGroup{}
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 		if err := documentClient.ScrollDocuments("testclient_scrolldocuments2", "doc", nil, docs); err != nil {
-			t.Fatalf("could not scroll documents: %s", err)
+			t.Errorf("could not scroll documents: %s", err)
 		}
 	}()
 	var counter int
@@ -0,0 +1,150 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors for the use with errors.Is. They match an *ElasticsearchError with the
+// corresponding status code or error type, e.g. errors.Is(err, ErrNotFound).
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrDocumentExists  = errors.New("document already exists")
+	ErrIndexNotFound   = errors.New("index not found")
+)
+
+// ErrorCause describes the cause of an Elasticsearch error.
+type ErrorCause struct {
+	Type     string      `json:"type"`
+	Reason   string      `json:"reason"`
+	Index    string      `json:"index"`
+	Shard    string      `json:"shard"`
+	CausedBy *ErrorCause `json:"caused_by"`
+}
+
+// UnmarshalJSON is the interface implementation for json Unmarshaler.
+// Elasticsearch returns the shard either as number or as string.
+func (e *ErrorCause) UnmarshalJSON(b []byte) error {
+	type errorCause ErrorCause
+	cause := struct {
+		*errorCause
+		Shard interface{} `json:"shard"`
+	}{errorCause: (*errorCause)(e)}
+	if err := json.Unmarshal(b, &cause); err != nil {
+		return err
+	}
+	if cause.Shard != nil {
+		e.Shard = fmt.Sprint(cause.Shard)
+	}
+	return nil
+}
+
+// ElasticsearchError is returned, if Elasticsearch answers a request with an error
+// status code. Use errors.As to access it through the wrapping errors of this package.
+type ElasticsearchError struct {
+	StatusCode int
+	Type       string
+	Reason     string
+	Index      string
+	Shard      string
+	RootCause  []ErrorCause
+	CausedBy   *ErrorCause
+	// Body is the raw response body.
+	Body []byte
+}
+
+// newElasticsearchError parses the error in the response body. Bodies without
+// error object, e.g. for missing documents, are accepted as well.
+func newElasticsearchError(statusCode int, body []byte) *ElasticsearchError {
+	result := &ElasticsearchError{StatusCode: statusCode, Body: body}
+	response := struct {
+		Error json.RawMessage `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &response); err != nil || response.Error == nil {
+		return result
+	}
+	cause := ErrorCause{}
+	if err := json.Unmarshal(response.Error, &cause); err != nil {
+		// very old versions return the error as string
+		json.Unmarshal(response.Error, &result.Reason)
+		return result
+	}
+	rootCause := struct {
+		RootCause []ErrorCause `json:"root_cause"`
+	}{}
+	json.Unmarshal(response.Error, &rootCause)
+	result.fromCause(cause)
+	result.RootCause = rootCause.RootCause
+	return result
+}
+
+// fromCause copies the fields of the cause into the error.
+func (e *ElasticsearchError) fromCause(cause ErrorCause) {
+	e.Type = cause.Type
+	e.Reason = cause.Reason
+	e.Index = cause.Index
+	e.Shard = cause.Shard
+	e.CausedBy = cause.CausedBy
+}
+
+func (e *ElasticsearchError) Error() string {
+	switch {
+	case e.Type != "":
+		return fmt.Sprintf("http status %d (%s: %s)", e.StatusCode, e.Type, e.Reason)
+	case e.Reason != "":
+		return fmt.Sprintf("http status %d (%s)", e.StatusCode, e.Reason)
+	case len(e.Body) > 0:
+		return fmt.Sprintf("http status %d (%s)", e.StatusCode, string(e.Body))
+	}
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// Is makes the error comparable with ErrNotFound, ErrConflict, ErrVersionConflict,
+// ErrDocumentExists and ErrIndexNotFound by errors.Is.
+func (e *ElasticsearchError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrVersionConflict:
+		return e.Type == "version_conflict_engine_exception"
+	case ErrDocumentExists:
+		return e.Type == "version_conflict_engine_exception" && strings.Contains(e.Reason, "document already exists")
+	case ErrIndexNotFound:
+		return e.Type == "index_not_found_exception"
+	}
+	return false
+}
+
+// IsNotFound returns true, if the error was caused by a 404 response, e.g. for
+// a missing document or index.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict returns true, if the error was caused by a 409 response.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsVersionConflict returns true, if a write failed because the document was
+// changed in between or already exists.
+func IsVersionConflict(err error) bool {
+	return errors.Is(err, ErrVersionConflict)
+}
+
+// IsDocumentExists returns true, if a create failed because the document already exists.
+func IsDocumentExists(err error) bool {
+	return errors.Is(err, ErrDocumentExists)
+}
+
+// IsIndexNotFound returns true, if the error was caused by a missing index.
+func IsIndexNotFound(err error) bool {
+	return errors.Is(err, ErrIndexNotFound)
+}
@@ -0,0 +1,108 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_ElasticsearchError(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		statusCode int
+		body       string
+		check      func(err error) bool
+		errorType  string
+		index      string
+		shard      string
+	}{
+		{
+			name:       "document not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"_index":"test","_type":"doc","_id":"1","found":false}`,
+			check:      func(err error) bool { return IsNotFound(err) && !IsIndexNotFound(err) },
+		},
+		{
+			name:       "index not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index","index_uuid":"_na_","index":"test"}],"type":"index_not_found_exception","reason":"no such index","index_uuid":"_na_","index":"test"},"status":404}`,
+			check:      func(err error) bool { return IsNotFound(err) && IsIndexNotFound(err) },
+			errorType:  "index_not_found_exception",
+			index:      "test",
+		},
+		{
+			name:       "version conflict",
+			statusCode: http.StatusConflict,
+			body:       `{"error":{"root_cause":[{"type":"version_conflict_engine_exception","reason":"[doc][1]: version conflict, document already exists (current version [1])","index_uuid":"a","shard":"3","index":"test"}],"type":"version_conflict_engine_exception","reason":"[doc][1]: version conflict, document already exists (current version [1])","index_uuid":"a","shard":"3","index":"test"},"status":409}`,
+			check: func(err error) bool {
+				return IsConflict(err) && IsVersionConflict(err) && IsDocumentExists(err) && !IsNotFound(err)
+			},
+			errorType: "version_conflict_engine_exception",
+			index:     "test",
+			shard:     "3",
+		},
+		{
+			name:       "bad request",
+			statusCode: http.StatusBadRequest,
+			body:       `{"error":{"root_cause":[{"type":"parsing_exception","reason":"unknown query [foo]"}],"type":"parsing_exception","reason":"unknown query [foo]","caused_by":{"type":"named_object_not_found_exception","reason":"unknown field [foo]"}},"status":400}`,
+			check: func(err error) bool {
+				var esErr *ElasticsearchError
+				return errors.As(err, &esErr) && esErr.CausedBy != nil && esErr.CausedBy.Type == "named_object_not_found_exception" && !IsConflict(err)
+			},
+			errorType: "parsing_exception",
+		},
+		{
+			name:       "no json",
+			statusCode: http.StatusBadGateway,
+			body:       `Bad Gateway`,
+			check:      func(err error) bool { return err.Error() == "could not get document: http status 502 (Bad Gateway)" },
+		},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.statusCode)
+			w.Write([]byte(test.body))
+		}))
+		client, err := NewClient(Config{URLs: []string{server.URL}, RetryPolicy: &RetryPolicy{MaxAttempts: 1}})
+		if err != nil {
+			t.Fatalf("%s: could not open client: %s", test.name, err)
+		}
+		_, err = client.GetDocument("test", "doc", "1")
+		server.Close()
+		var esErr *ElasticsearchError
+		if !errors.As(err, &esErr) {
+			t.Fatalf("%s: expected ElasticsearchError, got: %#v", test.name, err)
+		}
+		if !test.check(err) {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+		if esErr.StatusCode != test.statusCode || esErr.Type != test.errorType || esErr.Index != test.index || esErr.Shard != test.shard {
+			t.Fatalf("%s: unexpected error fields: %#v", test.name, esErr)
+		}
+		if test.errorType != "" && (len(esErr.RootCause) != 1 || esErr.RootCause[0].Type != test.errorType) {
+			t.Fatalf("%s: unexpected root cause: %#v", test.name, esErr.RootCause)
+		}
+	}
+}
+
+func TestClient_BulkItemError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"took":1,"errors":true,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse","shard":0}}}]}`))
+	}))
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	bulkErrors, err := client.InsertDocuments("test", "doc", map[string]map[string]interface{}{"1": {}, "2": {}})
+	if err != nil {
+		t.Fatalf("could not insert documents: %s", err)
+	}
+	var esErr *ElasticsearchError
+	if len(bulkErrors) != 1 || !errors.As(bulkErrors["2"], &esErr) {
+		t.Fatalf("expected ElasticsearchError for document 2, got: %#v", bulkErrors)
+	}
+	if esErr.StatusCode != http.StatusBadRequest || esErr.Type != "mapper_parsing_exception" || esErr.Shard != "0" {
+		t.Fatalf("unexpected error fields: %#v", esErr)
+	}
+}
@@ -0,0 +1,578 @@
+package estest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// aggregate computes the aggregations for the documents.
+func aggregate(aggs map[string]interface{}, docs []*document) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for name, definition := range aggs {
+		body, ok := definition.(map[string]interface{})
+		if !ok {
+			return nil, parsingError("Expected [START_OBJECT] under [%s]", name)
+		}
+		var sub map[string]interface{}
+		for _, key := range []string{"aggs", "aggregations"} {
+			if value, ok := body[key].(map[string]interface{}); ok {
+				sub = value
+			}
+		}
+		var aggType string
+		var params map[string]interface{}
+		for key, value := range body {
+			if key == "aggs" || key == "aggregations" || key == "meta" {
+				continue
+			}
+			if aggType != "" {
+				return nil, parsingError("Found two aggregation type definitions in [%s]: [%s] and [%s]", name, aggType, key)
+			}
+			aggType = key
+			params, _ = value.(map[string]interface{})
+		}
+		if params == nil {
+			return nil, parsingError("Missing definition for aggregation [%s]", name)
+		}
+		value, err := aggregateType(aggType, params, sub, docs)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+func aggregateType(aggType string, params, sub map[string]interface{}, docs []*document) (interface{}, error) {
+	field, _ := params["field"].(string)
+	switch aggType {
+	case "min", "max", "sum", "avg", "value_count", "cardinality":
+		return metric(aggType, field, docs), nil
+	case "terms":
+		return terms(params, field, sub, docs)
+	case "composite":
+		return composite(params, sub, docs)
+	case "date_histogram":
+		i, err := dateInterval(params)
+		if err != nil {
+			return nil, err
+		}
+		return dateHistogram(field, i, sub, docs)
+	case "auto_date_histogram":
+		return autoDateHistogram(params, field, sub, docs)
+	case "filter":
+		return filterBucket(params, sub, docs)
+	}
+	return nil, parsingError("Unknown aggregation type [%s]", aggType)
+}
+
+// metric computes a single value metric aggregation.
+func metric(aggType, field string, docs []*document) map[string]interface{} {
+	var count, numbers int
+	var sum float64
+	min, max := math.Inf(1), math.Inf(-1)
+	distinct := map[string]bool{}
+	for _, doc := range docs {
+		for _, value := range fieldValues(doc, field) {
+			count++
+			distinct[key(value)] = true
+			f, ok := numeric(value)
+			if !ok {
+				continue
+			}
+			numbers++
+			sum += f
+			min = math.Min(min, f)
+			max = math.Max(max, f)
+		}
+	}
+	var value interface{}
+	switch aggType {
+	case "min":
+		if numbers > 0 {
+			value = min
+		}
+	case "max":
+		if numbers > 0 {
+			value = max
+		}
+	case "sum":
+		value = sum
+	case "avg":
+		if numbers > 0 {
+			value = sum / float64(numbers)
+		}
+	case "value_count":
+		value = count
+	case "cardinality":
+		value = len(distinct)
+	}
+	return map[string]interface{}{"value": value}
+}
+
+// key returns a string, that identifies a value for grouping.
+func key(value interface{}) string {
+	if f, ok := value.(json.Number); ok {
+		if n, err := f.Float64(); err == nil {
+			return strconv.FormatFloat(n, 'g', -1, 64)
+		}
+	}
+	return fmt.Sprintf("%T:%v", value, value)
+}
+
+// bucketKey returns the key of a bucket for a value, numbers are returned as
+// numbers and booleans as 0 and 1 like Elasticsearch does.
+func bucketKey(value interface{}) interface{} {
+	if b, ok := value.(bool); ok {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return value
+}
+
+// group groups the documents by the values of the field.
+func group(field string, docs []*document) (map[string][]*document, map[string]interface{}) {
+	groups := map[string][]*document{}
+	values := map[string]interface{}{}
+	for _, doc := range docs {
+		seen := map[string]bool{}
+		for _, value := range fieldValues(doc, field) {
+			if _, ok := value.(map[string]interface{}); ok {
+				continue
+			}
+			k := key(value)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			groups[k] = append(groups[k], doc)
+			values[k] = value
+		}
+	}
+	return groups, values
+}
+
+// bucket returns a bucket with the sub aggregations of its documents.
+func bucket(bucketKey interface{}, docs []*document, sub map[string]interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{
+		"key":       bucketKey,
+		"doc_count": len(docs),
+	}
+	if sub != nil {
+		aggregations, err := aggregate(sub, docs)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range aggregations {
+			result[name] = value
+		}
+	}
+	return result, nil
+}
+
+// terms returns the most frequent values of the field. Buckets are ordered by
+// count and then by key.
+func terms(params map[string]interface{}, field string, sub map[string]interface{}, docs []*document) (interface{}, error) {
+	size := 10
+	if value, ok := params["size"]; ok {
+		f, ok := numeric(value)
+		if !ok {
+			return nil, parsingError("[terms] failed to parse field [size]")
+		}
+		size = int(f)
+	}
+	groups, values := group(field, docs)
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(groups[keys[i]]) != len(groups[keys[j]]) {
+			return len(groups[keys[i]]) > len(groups[keys[j]])
+		}
+		c, _ := compareValues(values[keys[i]], values[keys[j]])
+		return c < 0
+	})
+	var other int
+	buckets := []interface{}{}
+	for i, k := range keys {
+		if i >= size {
+			other += len(groups[k])
+			continue
+		}
+		b, err := bucket(bucketKey(values[k]), groups[k], sub)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := values[k].(bool); ok {
+			b["key_as_string"] = strconv.FormatBool(s)
+		}
+		buckets = append(buckets, b)
+	}
+	return map[string]interface{}{
+		"doc_count_error_upper_bound": 0,
+		"sum_other_doc_count":         other,
+		"buckets":                     buckets,
+	}, nil
+}
+
+// compositeSource is a terms source of a composite aggregation.
+type compositeSource struct {
+	name  string
+	field string
+	desc  bool
+}
+
+// composite pages through all combinations of the values of the sources. The
+// sources are a list of objects with a single source, or a single object.
+func composite(params, sub map[string]interface{}, docs []*document) (interface{}, error) {
+	var definitions []map[string]interface{}
+	switch value := params["sources"].(type) {
+	case []interface{}:
+		for _, element := range value {
+			definition, ok := element.(map[string]interface{})
+			if !ok {
+				return nil, parsingError("[composite] sources must be objects")
+			}
+			definitions = append(definitions, definition)
+		}
+	case map[string]interface{}:
+		definitions = append(definitions, value)
+	default:
+		return nil, parsingError("Required [sources]")
+	}
+	var sources []compositeSource
+	for _, definition := range definitions {
+		for _, name := range sortedKeys(definition) {
+			source, _ := definition[name].(map[string]interface{})
+			termsSource, ok := source["terms"].(map[string]interface{})
+			if !ok {
+				return nil, parsingError("estest only supports terms sources in composite aggregations")
+			}
+			field, _ := termsSource["field"].(string)
+			sources = append(sources, compositeSource{name: name, field: field, desc: termsSource["order"] == "desc"})
+		}
+	}
+	size := 10
+	if value, ok := numeric(params["size"]); ok {
+		size = int(value)
+	}
+	after, _ := params["after"].(map[string]interface{})
+
+	type combination struct {
+		values []interface{}
+		docs   []*document
+	}
+	combinations := map[string]*combination{}
+	for _, doc := range docs {
+		keys := [][]interface{}{nil}
+		for _, source := range sources {
+			values := fieldValues(doc, source.field)
+			var next [][]interface{}
+			for _, prefix := range keys {
+				seen := map[string]bool{}
+				for _, value := range values {
+					if seen[key(value)] {
+						continue
+					}
+					seen[key(value)] = true
+					next = append(next, append(append([]interface{}(nil), prefix...), value))
+				}
+			}
+			keys = next
+		}
+		for _, values := range keys {
+			var parts []string
+			for _, value := range values {
+				parts = append(parts, key(value))
+			}
+			k := strings.Join(parts, "\x00")
+			if combinations[k] == nil {
+				combinations[k] = &combination{values: values}
+			}
+			combinations[k].docs = append(combinations[k].docs, doc)
+		}
+	}
+	compare := func(a, b []interface{}) int {
+		for i, source := range sources {
+			c, _ := compareValues(a[i], b[i])
+			if source.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	var sorted []*combination
+	for _, c := range combinations {
+		if after != nil {
+			var afterValues []interface{}
+			for _, source := range sources {
+				afterValues = append(afterValues, after[source.name])
+			}
+			if compare(c.values, afterValues) <= 0 {
+				continue
+			}
+		}
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return compare(sorted[i].values, sorted[j].values) < 0
+	})
+	if len(sorted) > size {
+		sorted = sorted[:size]
+	}
+	buckets := []interface{}{}
+	var afterKey map[string]interface{}
+	for _, c := range sorted {
+		k := map[string]interface{}{}
+		for i, source := range sources {
+			k[source.name] = bucketKey(c.values[i])
+		}
+		b, err := bucket(k, c.docs, sub)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+		afterKey = k
+	}
+	result := map[string]interface{}{"buckets": buckets}
+	if afterKey != nil {
+		result["after_key"] = afterKey
+	}
+	return result, nil
+}
+
+// interval is a calendar or fixed interval of a date histogram.
+type interval struct {
+	calendar string
+	fixed    time.Duration
+}
+
+// calendarUnits maps the calendar intervals to their units.
+var calendarUnits = map[string]string{
+	"minute": "minute", "1m": "minute",
+	"hour": "hour", "1h": "hour",
+	"day": "day", "1d": "day",
+	"week": "week", "1w": "week",
+	"month": "month", "1M": "month",
+	"quarter": "quarter", "1q": "quarter",
+	"year": "year", "1y": "year",
+	"second": "second", "1s": "second",
+}
+
+// dateInterval parses the interval, calendar_interval or fixed_interval of a date histogram.
+func dateInterval(params map[string]interface{}) (interval, error) {
+	for _, key := range []string{"calendar_interval", "interval", "fixed_interval"} {
+		value, ok := params[key].(string)
+		if !ok {
+			continue
+		}
+		if unit, ok := calendarUnits[value]; ok && key != "fixed_interval" {
+			return interval{calendar: unit}, nil
+		}
+		if d, ok := parseFixedInterval(value); ok && key != "calendar_interval" {
+			return interval{fixed: d}, nil
+		}
+		return interval{}, badRequest("failed to parse setting [date_histogram.%s] with value [%s]", key, value)
+	}
+	return interval{}, badRequest("Invalid interval specified, must be non-null and non-empty")
+}
+
+// parseFixedInterval parses intervals like 30s, 10m or 7d.
+func parseFixedInterval(value string) (time.Duration, bool) {
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{{"ms", time.Millisecond}, {"s", time.Second}, {"m", time.Minute}, {"h", time.Hour}, {"d", 24 * time.Hour}}
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(value, unit.suffix))
+			if err != nil || n <= 0 {
+				return 0, false
+			}
+			return time.Duration(n) * unit.unit, true
+		}
+	}
+	return 0, false
+}
+
+// floor returns the start of the bucket containing t.
+func (i interval) floor(t time.Time) time.Time {
+	t = t.UTC()
+	switch i.calendar {
+	case "second":
+		return t.Truncate(time.Second)
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	step := int64(i.fixed / time.Millisecond)
+	ms -= ((ms % step) + step) % step
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
+// next returns the start of the bucket after the bucket starting at t.
+func (i interval) next(t time.Time) time.Time {
+	switch i.calendar {
+	case "second":
+		return t.Add(time.Second)
+	case "minute":
+		return t.Add(time.Minute)
+	case "hour":
+		return t.Add(time.Hour)
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	case "quarter":
+		return t.AddDate(0, 3, 0)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	}
+	return t.Add(i.fixed)
+}
+
+// dateValue returns the time of a date value, which is a date string or
+// milliseconds since the epoch.
+func dateValue(value interface{}) (time.Time, bool) {
+	if s, ok := value.(string); ok {
+		if t, ok := parseDate(s); ok {
+			return t, true
+		}
+	}
+	ms, ok := numeric(value)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC(), true
+}
+
+// dateBuckets groups the documents into the buckets of the interval. Empty buckets
+// between the first and the last bucket are included.
+func dateBuckets(field string, i interval, docs []*document) ([]time.Time, map[time.Time][]*document) {
+	groups := map[time.Time][]*document{}
+	var first, last time.Time
+	for _, doc := range docs {
+		seen := map[time.Time]bool{}
+		for _, value := range fieldValues(doc, field) {
+			t, ok := dateValue(value)
+			if !ok {
+				continue
+			}
+			start := i.floor(t)
+			if seen[start] {
+				continue
+			}
+			seen[start] = true
+			groups[start] = append(groups[start], doc)
+			if first.IsZero() || start.Before(first) {
+				first = start
+			}
+			if start.After(last) {
+				last = start
+			}
+		}
+	}
+	var starts []time.Time
+	if len(groups) == 0 {
+		return starts, groups
+	}
+	for t := first; !t.After(last); t = i.next(t) {
+		starts = append(starts, t)
+	}
+	return starts, groups
+}
+
+func dateHistogram(field string, i interval, sub map[string]interface{}, docs []*document) (interface{}, error) {
+	starts, groups := dateBuckets(field, i, docs)
+	buckets := []interface{}{}
+	for _, start := range starts {
+		b, err := bucket(start.UnixNano()/int64(time.Millisecond), groups[start], sub)
+		if err != nil {
+			return nil, err
+		}
+		b["key_as_string"] = start.Format("2006-01-02T15:04:05.000Z")
+		buckets = append(buckets, b)
+	}
+	return map[string]interface{}{"buckets": buckets}, nil
+}
+
+// autoIntervals are the intervals tried by the auto_date_histogram in this order.
+var autoIntervals = []struct {
+	interval interval
+	name     string
+}{
+	{interval{calendar: "second"}, "1s"},
+	{interval{calendar: "minute"}, "1m"},
+	{interval{calendar: "hour"}, "1h"},
+	{interval{calendar: "day"}, "1d"},
+	{interval{calendar: "month"}, "1M"},
+	{interval{calendar: "year"}, "1y"},
+}
+
+// autoDateHistogram uses the smallest interval with at most the requested number
+// of buckets.
+func autoDateHistogram(params map[string]interface{}, field string, sub map[string]interface{}, docs []*document) (interface{}, error) {
+	target := 10
+	if value, ok := numeric(params["buckets"]); ok && value > 0 {
+		target = int(value)
+	}
+	for n, auto := range autoIntervals {
+		starts, _ := dateBuckets(field, auto.interval, docs)
+		if len(starts) > target && n < len(autoIntervals)-1 {
+			continue
+		}
+		result, err := dateHistogram(field, auto.interval, sub, docs)
+		if err != nil {
+			return nil, err
+		}
+		result.(map[string]interface{})["interval"] = auto.name
+		return result, nil
+	}
+	return nil, nil
+}
+
+// filterBucket returns a single bucket with the documents matching the filter.
+func filterBucket(query, sub map[string]interface{}, docs []*document) (interface{}, error) {
+	var matched []*document
+	for _, doc := range docs {
+		ok, err := matches(query, doc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+	result, err := bucket(nil, matched, sub)
+	if err != nil {
+		return nil, err
+	}
+	delete(result, "key")
+	return result, nil
+}
@@ -0,0 +1,146 @@
+package estest
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// aliased returns the indices with the alias.
+func (s *Server) aliased(alias string) []*index {
+	var result []*index
+	for _, ix := range s.indices {
+		if ix.aliases[alias] {
+			result = append(result, ix)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// aliasesResult returns the aliases of the index for responses.
+func (ix *index) aliasesResult() map[string]interface{} {
+	result := map[string]interface{}{}
+	for alias := range ix.aliases {
+		result[alias] = map[string]interface{}{}
+	}
+	return result
+}
+
+// matchAlias returns true, if the alias matches one of the comma separated names
+// or wildcard patterns. An empty list matches all aliases.
+func matchAlias(names, alias string) bool {
+	if names == "" || names == "_all" {
+		return true
+	}
+	for _, name := range strings.Split(names, ",") {
+		if ok, _ := path.Match(name, alias); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func aliasesNotFound(names string) *esError {
+	return &esError{status: http.StatusNotFound, errType: "aliases_not_found_exception", reason: "aliases [" + names + "] missing"}
+}
+
+// alias handles the _alias api of the indices to add, get, check and remove aliases.
+func (s *Server) alias(r *request, indices, names string) (int, interface{}, error) {
+	resolved, err := s.resolve(indices)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch r.method {
+	case http.MethodPut, http.MethodPost:
+		if names == "" || strings.ContainsAny(names, "*?,") {
+			return 0, nil, badRequest("Invalid alias name [%s]", names)
+		}
+		for _, ix := range resolved {
+			ix.aliases[names] = true
+		}
+		return http.StatusOK, acknowledged(), nil
+	case http.MethodDelete:
+		var removed bool
+		for _, ix := range resolved {
+			for alias := range ix.aliases {
+				if matchAlias(names, alias) {
+					delete(ix.aliases, alias)
+					removed = true
+				}
+			}
+		}
+		if !removed {
+			return 0, nil, aliasesNotFound(names)
+		}
+		return http.StatusOK, acknowledged(), nil
+	case http.MethodGet, http.MethodHead:
+		result := map[string]interface{}{}
+		for _, ix := range resolved {
+			aliases := map[string]interface{}{}
+			for alias := range ix.aliases {
+				if matchAlias(names, alias) {
+					aliases[alias] = map[string]interface{}{}
+				}
+			}
+			if len(aliases) > 0 || names == "" {
+				result[ix.name] = map[string]interface{}{"aliases": aliases}
+			}
+		}
+		if len(result) == 0 && names != "" {
+			return 0, nil, aliasesNotFound(names)
+		}
+		return http.StatusOK, result, nil
+	}
+	return 0, nil, badRequest("method [%s] is not allowed", r.method)
+}
+
+// aliases handles the _aliases api with add and remove actions.
+func (s *Server) aliases(r *request) (int, interface{}, error) {
+	if r.method != http.MethodPost {
+		return 0, nil, badRequest("method [%s] is not allowed", r.method)
+	}
+	body := struct {
+		Actions []map[string]struct {
+			Index   string   `json:"index"`
+			Indices []string `json:"indices"`
+			Alias   string   `json:"alias"`
+			Aliases []string `json:"aliases"`
+		} `json:"actions"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return 0, nil, err
+	}
+	for _, action := range body.Actions {
+		for op, params := range action {
+			indices := append([]string{params.Index}, params.Indices...)
+			aliases := append([]string{params.Alias}, params.Aliases...)
+			for _, names := range indices {
+				if names == "" {
+					continue
+				}
+				resolved, err := s.resolve(names)
+				if err != nil {
+					return 0, nil, err
+				}
+				for _, ix := range resolved {
+					for _, alias := range aliases {
+						switch {
+						case alias == "":
+						case op == "add":
+							ix.aliases[alias] = true
+						case op == "remove":
+							delete(ix.aliases, alias)
+						default:
+							return 0, nil, badRequest("Unsupported alias action [%s]", op)
+						}
+					}
+				}
+			}
+		}
+	}
+	return http.StatusOK, acknowledged(), nil
+}
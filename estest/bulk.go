@@ -0,0 +1,114 @@
+package estest
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+)
+
+// bulkAction is the action and metadata line of a bulk request.
+type bulkAction struct {
+	Index   string `json:"_index"`
+	Type    string `json:"_type"`
+	ID      string `json:"_id"`
+	Routing string `json:"routing"`
+}
+
+// bulk handles the _bulk api with the actions index, create, update and delete.
+func (s *Server) bulk(r *request, name, doctype string) (int, interface{}, error) {
+	if r.method != http.MethodPost && r.method != http.MethodPut {
+		return 0, nil, badRequest("method [%s] is not allowed", r.method)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(r.body))
+	scanner.Buffer(nil, len(r.body)+1)
+	var items []interface{}
+	var hasErrors bool
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var action map[string]*bulkAction
+		if err := decodeJSON(line, &action); err != nil || len(action) != 1 {
+			return 0, nil, badRequest("Malformed action/metadata line, expected a single action")
+		}
+		for op, meta := range action {
+			if meta == nil {
+				meta = &bulkAction{}
+			}
+			if meta.Index == "" {
+				meta.Index = name
+			}
+			if meta.Type == "" {
+				meta.Type = doctype
+			}
+			var body []byte
+			if op != "delete" {
+				if !scanner.Scan() {
+					return 0, nil, badRequest("The bulk request must be terminated by a newline [\\n]")
+				}
+				body = append([]byte(nil), scanner.Bytes()...)
+			}
+			item, err := s.bulkItem(op, meta, body)
+			if err != nil {
+				return 0, nil, err
+			}
+			if item["error"] != nil {
+				hasErrors = true
+			}
+			items = append(items, map[string]interface{}{op: item})
+		}
+	}
+	return http.StatusOK, map[string]interface{}{
+		"took":   1,
+		"errors": hasErrors,
+		"items":  items,
+	}, nil
+}
+
+// bulkItem executes a single action of a bulk request and returns its result.
+func (s *Server) bulkItem(op string, meta *bulkAction, body []byte) (map[string]interface{}, error) {
+	sub := &request{method: http.MethodPost, body: body, query: map[string][]string{}}
+	var status int
+	var result interface{}
+	var err error
+	switch op {
+	case "index":
+		if meta.ID == "" {
+			meta.ID = generateID()
+		}
+		status, result, err = s.write(sub, meta.Index, meta.Type, meta.ID, false)
+	case "create":
+		if meta.ID == "" {
+			meta.ID = generateID()
+		}
+		status, result, err = s.write(sub, meta.Index, meta.Type, meta.ID, true)
+	case "update":
+		status, result, err = s.update(sub, meta.Index, meta.Type, meta.ID)
+	case "delete":
+		status, result, err = s.delete(sub, meta.Index, meta.Type, meta.ID)
+	default:
+		return nil, badRequest("Malformed action/metadata line, expected one of [create, delete, index, update] but found [%s]", op)
+	}
+	if err != nil {
+		e, ok := err.(*esError)
+		if !ok {
+			e = &esError{status: http.StatusInternalServerError, errType: "exception", reason: err.Error()}
+		}
+		item := map[string]interface{}{
+			"_index": meta.Index,
+			"_id":    meta.ID,
+			"status": e.status,
+			"error":  e.cause(),
+		}
+		if ix, ok := s.indices[meta.Index]; ok {
+			if doctype := s.doctype(ix); doctype != "" {
+				item["_type"] = doctype
+			}
+		}
+		return item, nil
+	}
+	item := result.(map[string]interface{})
+	item["status"] = status
+	return item, nil
+}
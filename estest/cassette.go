@@ -0,0 +1,283 @@
+package estest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mode is the mode of a Cassette.
+type Mode int
+
+// Modes of a Cassette
+const (
+	// ModeReplay answers all requests with the recorded responses and never
+	// connects to Elasticsearch.
+	ModeReplay Mode = iota
+	// ModeRecord sends all requests to Elasticsearch and records them.
+	ModeRecord
+)
+
+// Interaction is a recorded request with its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request of an Interaction. Headers are not recorded to
+// keep credentials out of the cassette.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response of an Interaction.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Cassette is a http.RoundTripper, that records requests and responses to a file
+// and replays them. Use it as Transport of the client config:
+//
+//	cassette, err := estest.NewCassette("testdata/search.json", estest.ModeReplay)
+//	client, err := elasticsearch.NewClient(elasticsearch.Config{URLs: urls, Transport: cassette})
+//
+// On replay, every request must match an unused recorded request by method, path,
+// query and body. JSON and NDJSON bodies are compared after normalization, so the
+// order of object keys does not matter. Requests with equal content are answered
+// in the order they were recorded.
+type Cassette struct {
+	// Transport sends the requests in ModeRecord. Default: http.DefaultTransport.
+	Transport    http.RoundTripper
+	path         string
+	mode         Mode
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewCassette creates a cassette for the file at path. In ModeReplay, the
+// interactions are loaded from the file. In ModeRecord, the file is written by Save.
+func NewCassette(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode}
+	if mode != ModeReplay {
+		return c, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cassette: %w", err)
+	}
+	if err := json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, fmt.Errorf("could not unmarshal cassette: %w", err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// RoundTrip is the interface implementation for http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := RecordedRequest{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery, Body: body}
+	if c.mode == ModeRecord {
+		return c.record(req, recorded)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		if !c.used[i] && matchRequest(interaction.Request, recorded) {
+			c.used[i] = true
+			return replay(req, interaction.Response), nil
+		}
+	}
+	return nil, fmt.Errorf("estest: no recorded interaction for %s %s?%s with body %q", recorded.Method, recorded.Path, recorded.Query, recorded.Body)
+}
+
+// record sends the request and records the response.
+func (c *Cassette) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var body io.Reader = res.Body
+	if res.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response: %w", err)
+	}
+	header := res.Header.Clone()
+	for _, key := range []string{"Content-Encoding", "Content-Length", "Date"} {
+		header.Del(key)
+	}
+	response := RecordedResponse{StatusCode: res.StatusCode, Header: header, Body: string(b)}
+	c.mu.Lock()
+	c.interactions = append(c.interactions, &Interaction{Request: recorded, Response: response})
+	c.mu.Unlock()
+	return replay(req, response), nil
+}
+
+// Save writes the recorded interactions to the file of the cassette.
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return errors.New("could not save cassette: cassette is not recording")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interactions := c.interactions
+	if interactions == nil {
+		interactions = []*Interaction{}
+	}
+	b, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal cassette: %w", err)
+	}
+	if err := ioutil.WriteFile(c.path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("could not write cassette: %w", err)
+	}
+	return nil
+}
+
+// Unused returns the recorded interactions, that were not replayed yet. Tests
+// can check it to make sure all expected requests were done.
+func (c *Cassette) Unused() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var result []Interaction
+	for i, interaction := range c.interactions {
+		if i < len(c.used) && !c.used[i] {
+			result = append(result, *interaction)
+		}
+	}
+	return result
+}
+
+// requestBody reads the body of the request and replaces it with a copy, so it
+// can still be sent. Compressed bodies are returned decompressed.
+func requestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("could not read request body: %w", err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return "", fmt.Errorf("could not decompress request body: %w", err)
+		}
+		defer gz.Close()
+		if b, err = ioutil.ReadAll(gz); err != nil {
+			return "", fmt.Errorf("could not decompress request body: %w", err)
+		}
+	}
+	return string(b), nil
+}
+
+// replay creates the response for a recorded response.
+func replay(req *http.Request, recorded RecordedResponse) *http.Response {
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
+
+// matchRequest returns true, if the recorded request matches the request.
+func matchRequest(recorded, req RecordedRequest) bool {
+	if recorded.Method != req.Method || recorded.Path != req.Path {
+		return false
+	}
+	q1, err1 := url.ParseQuery(recorded.Query)
+	q2, err2 := url.ParseQuery(req.Query)
+	if err1 != nil || err2 != nil || !reflect.DeepEqual(q1, q2) {
+		return false
+	}
+	return normalizeBody(recorded.Body) == normalizeBody(req.Body)
+}
+
+// normalizeBody returns the JSON or NDJSON body with sorted keys and without
+// whitespace. Other bodies are returned unchanged.
+func normalizeBody(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return ""
+	}
+	if normalized, ok := normalizeJSON(body); ok {
+		return normalized
+	}
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(nil, len(body)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		normalized, ok := normalizeJSON(line)
+		if !ok {
+			return body
+		}
+		lines = append(lines, normalized)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func normalizeJSON(s string) (string, bool) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return "", false
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", false
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// ensure the interface is implemented
+var _ http.RoundTripper = (*Cassette)(nil)
@@ -0,0 +1,119 @@
+package estest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// send sends the request with the transport and returns the status code and body.
+func send(t *testing.T, transport http.RoundTripper, method, url, body string, gzipped bool) (int, string, error) {
+	t.Helper()
+	data := []byte(body)
+	if gzipped {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+		data = buf.Bytes()
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	res, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("could not read response: %s", err)
+	}
+	return res.StatusCode, string(b), nil
+}
+
+func TestCassette(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := NewCassette(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("could not create recorder: %s", err)
+	}
+	requests := []struct {
+		method, path, body string
+		gzipped            bool
+	}{
+		{http.MethodPut, "/test/doc/1?refresh=true", `{"name":"alpha","count":1}`, false},
+		{http.MethodPost, "/_bulk", "{\"index\":{\"_index\":\"test\",\"_type\":\"doc\",\"_id\":\"2\"}}\n{\"name\":\"beta\"}\n", true},
+		{http.MethodPost, "/test/_search?size=10&from=0", `{"query":{"term":{"name":"alpha"}}}`, false},
+		{http.MethodGet, "/test/doc/3", "", false},
+	}
+	var recorded []string
+	for _, r := range requests {
+		_, body, err := send(t, recorder, r.method, s.URL+r.path, r.body, r.gzipped)
+		if err != nil {
+			t.Fatalf("could not record %s %s: %s", r.method, r.path, err)
+		}
+		recorded = append(recorded, body)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("could not save cassette: %s", err)
+	}
+	s.Close()
+
+	player, err := NewCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("could not load cassette: %s", err)
+	}
+	replayed := []struct {
+		method, path, body string
+		gzipped            bool
+	}{
+		{http.MethodPut, "/test/doc/1?refresh=true", `{"count":1, "name":"alpha"}`, true},
+		{http.MethodPost, "/_bulk", "{\"index\":{\"_id\":\"2\",\"_type\":\"doc\",\"_index\":\"test\"}}\n{ \"name\": \"beta\" }\n", false},
+		{http.MethodPost, "/test/_search?from=0&size=10", `{"query": {"term": {"name": "alpha"}}}`, false},
+		{http.MethodGet, "/test/doc/3", "", false},
+	}
+	for i, r := range replayed {
+		status, body, err := send(t, player, r.method, "http://replay.invalid"+r.path, r.body, r.gzipped)
+		if err != nil {
+			t.Fatalf("could not replay %s %s: %s", r.method, r.path, err)
+		}
+		if body != recorded[i] {
+			t.Errorf("%s %s: expected %s, got %s", r.method, r.path, recorded[i], body)
+		}
+		if i == 3 && status != http.StatusNotFound {
+			t.Errorf("expected recorded status 404, got %d", status)
+		}
+	}
+	if unused := player.Unused(); len(unused) != 0 {
+		t.Errorf("expected all interactions to be used, got %v", unused)
+	}
+
+	player, _ = NewCassette(path, ModeReplay)
+	for _, r := range []struct{ method, path, body string }{
+		{http.MethodPut, "/test/doc/1?refresh=false", `{"name":"alpha","count":1}`},
+		{http.MethodPut, "/test/doc/1?refresh=true", `{"name":"alpha","count":2}`},
+		{http.MethodPost, "/test/doc/1?refresh=true", `{"name":"alpha","count":1}`},
+		{http.MethodPost, "/_bulk", "{\"index\":{\"_index\":\"test\",\"_type\":\"doc\",\"_id\":\"2\"}}\n"},
+	} {
+		if _, _, err := send(t, player, r.method, "http://replay.invalid"+r.path, r.body, false); err == nil {
+			t.Errorf("%s %s: expected mismatch for body %s", r.method, r.path, r.body)
+		}
+	}
+	if unused := player.Unused(); len(unused) != len(requests) {
+		t.Errorf("expected %d unused interactions, got %d", len(requests), len(unused))
+	}
+	if err := player.Save(); err == nil {
+		t.Error("expected error when saving a replaying cassette")
+	}
+}
@@ -0,0 +1,609 @@
+package estest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// index is an index with its documents in the order of insertion.
+type index struct {
+	name    string
+	doctype string
+	docs    map[string]*document
+	ids     []string
+	seqNo   int64
+	aliases map[string]bool
+}
+
+// document is a stored document with its metadata.
+type document struct {
+	index       string
+	id          string
+	source      map[string]interface{}
+	version     int64
+	seqNo       int64
+	primaryTerm int64
+	position    int
+}
+
+func newIndex(name string) *index {
+	return &index{name: name, docs: map[string]*document{}, aliases: map[string]bool{}}
+}
+
+// documents returns all documents in the order of insertion.
+func (ix *index) documents() []*document {
+	result := make([]*document, 0, len(ix.ids))
+	for _, id := range ix.ids {
+		result = append(result, ix.docs[id])
+	}
+	return result
+}
+
+// put stores the source as new version of the document.
+func (ix *index) put(id string, source map[string]interface{}) (*document, bool) {
+	doc, exists := ix.docs[id]
+	if !exists {
+		doc = &document{index: ix.name, id: id, primaryTerm: 1, position: len(ix.ids)}
+		ix.docs[id] = doc
+		ix.ids = append(ix.ids, id)
+	}
+	doc.source = source
+	doc.version++
+	doc.seqNo = ix.seqNo
+	ix.seqNo++
+	return doc, !exists
+}
+
+// remove deletes the document and returns the deleted document.
+func (ix *index) remove(id string) (*document, bool) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return nil, false
+	}
+	delete(ix.docs, id)
+	for i, existing := range ix.ids {
+		if existing == id {
+			ix.ids = append(ix.ids[:i], ix.ids[i+1:]...)
+			break
+		}
+	}
+	for i, existing := range ix.ids[doc.position:] {
+		ix.docs[existing].position = doc.position + i
+	}
+	doc.version++
+	doc.seqNo = ix.seqNo
+	ix.seqNo++
+	return doc, true
+}
+
+// resolve returns the indices matching a comma separated list of names and
+// wildcard patterns. Missing names without wildcards are an error.
+func (s *Server) resolve(names string) ([]*index, error) {
+	var result []*index
+	seen := map[string]bool{}
+	for _, name := range strings.Split(names, ",") {
+		if name == "_all" {
+			name = "*"
+		}
+		if !strings.ContainsAny(name, "*?") {
+			indices := s.aliased(name)
+			if ix, ok := s.indices[name]; ok {
+				indices = []*index{ix}
+			}
+			if len(indices) == 0 {
+				return nil, indexNotFound(name)
+			}
+			for _, ix := range indices {
+				if !seen[ix.name] {
+					seen[ix.name] = true
+					result = append(result, ix)
+				}
+			}
+			continue
+		}
+		var matches []string
+		for existing := range s.indices {
+			if ok, _ := path.Match(name, existing); ok && !seen[existing] {
+				matches = append(matches, existing)
+			}
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			seen[match] = true
+			result = append(result, s.indices[match])
+		}
+	}
+	return result, nil
+}
+
+// writableIndex returns the index for a write and creates it, if it does not exist.
+func (s *Server) writableIndex(name, doctype string) (*index, error) {
+	if name == "" || strings.ContainsAny(name, "*?,") || strings.ToLower(name) != name {
+		return nil, &esError{status: http.StatusBadRequest, errType: "invalid_index_name_exception", reason: "Invalid index name [" + name + "]", index: name}
+	}
+	ix, ok := s.indices[name]
+	if aliased := s.aliased(name); !ok && len(aliased) > 1 {
+		return nil, badRequest("no write index is defined for alias [%s]", name)
+	} else if !ok && len(aliased) == 1 {
+		ix, ok = aliased[0], true
+	}
+	if !ok {
+		ix = newIndex(name)
+		s.indices[name] = ix
+	}
+	if s.typeless() || doctype == "" {
+		return ix, nil
+	}
+	if ix.doctype == "" {
+		ix.doctype = doctype
+	} else if ix.doctype != doctype {
+		return nil, badRequest("Rejecting mapping update to [%s] as the final mapping would have more than 1 type: [%s, %s]", name, ix.doctype, doctype)
+	}
+	return ix, nil
+}
+
+// meta returns the metadata fields of a document for responses.
+func (s *Server) meta(ix *index, doc *document) map[string]interface{} {
+	result := map[string]interface{}{
+		"_index":        ix.name,
+		"_id":           doc.id,
+		"_version":      doc.version,
+		"_seq_no":       doc.seqNo,
+		"_primary_term": doc.primaryTerm,
+	}
+	if doctype := s.doctype(ix); doctype != "" {
+		result["_type"] = doctype
+	}
+	return result
+}
+
+// readMeta returns the metadata fields of a document for get responses.
+func (s *Server) readMeta(ix *index, doc *document) map[string]interface{} {
+	result := s.meta(ix, doc)
+	if !s.seqNoPrimaryTerm() {
+		delete(result, "_seq_no")
+		delete(result, "_primary_term")
+	}
+	return result
+}
+
+// doctype returns the _type field for responses, which was removed in Elasticsearch 8.
+func (s *Server) doctype(ix *index) string {
+	switch {
+	case s.major >= 8:
+		return ""
+	case s.typeless() || ix.doctype == "":
+		return "_doc"
+	}
+	return ix.doctype
+}
+
+// index handles the apis for a whole index.
+func (s *Server) index(r *request, name string) (int, interface{}, error) {
+	switch r.method {
+	case http.MethodPut:
+		if _, ok := s.indices[name]; ok {
+			return 0, nil, &esError{status: http.StatusBadRequest, errType: "resource_already_exists_exception", reason: "index [" + name + "] already exists", index: name}
+		}
+		body := struct {
+			Aliases map[string]interface{} `json:"aliases"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return 0, nil, err
+		}
+		ix, err := s.writableIndex(name, "")
+		if err != nil {
+			return 0, nil, err
+		}
+		for alias := range body.Aliases {
+			ix.aliases[alias] = true
+		}
+		return http.StatusOK, map[string]interface{}{"acknowledged": true, "shards_acknowledged": true, "index": name}, nil
+	case http.MethodGet, http.MethodHead:
+		indices, err := s.resolve(name)
+		if err != nil {
+			return 0, nil, err
+		}
+		result := map[string]interface{}{}
+		for _, ix := range indices {
+			result[ix.name] = map[string]interface{}{"aliases": ix.aliasesResult(), "mappings": map[string]interface{}{}, "settings": map[string]interface{}{}}
+		}
+		return http.StatusOK, result, nil
+	case http.MethodDelete:
+		indices, err := s.resolve(name)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, ix := range indices {
+			delete(s.indices, ix.name)
+		}
+		return http.StatusOK, acknowledged(), nil
+	}
+	return 0, nil, badRequest("method [%s] is not allowed", r.method)
+}
+
+// document handles the apis for a single document.
+func (s *Server) document(r *request, name, doctype, id string) (int, interface{}, error) {
+	switch {
+	case (r.method == http.MethodPost || r.method == http.MethodPut) && id != "":
+		if r.query.Get("op_type") == "create" {
+			return s.documentAPI(r, name, doctype, id, "_create")
+		}
+		return s.write(r, name, doctype, id, false)
+	case r.method == http.MethodPost:
+		return s.write(r, name, doctype, generateID(), true)
+	case id == "":
+		break
+	case r.method == http.MethodGet || r.method == http.MethodHead:
+		return s.get(r, name, id)
+	case r.method == http.MethodDelete:
+		return s.delete(r, name, doctype, id)
+	}
+	return 0, nil, badRequest("no handler found for uri [/%s/%s] and method [%s]", name, doctype, r.method)
+}
+
+// documentAPI handles the _update and _create apis of a document.
+func (s *Server) documentAPI(r *request, name, doctype, id, api string) (int, interface{}, error) {
+	switch {
+	case api == "_create" && (r.method == http.MethodPut || r.method == http.MethodPost):
+		return s.write(r, name, doctype, id, true)
+	case api == "_update" && r.method == http.MethodPost:
+		return s.update(r, name, doctype, id)
+	}
+	return 0, nil, badRequest("no handler found for uri [/%s/%s/%s] and method [%s]", name, id, api, r.method)
+}
+
+// write indexes the document in the request body. With create, existing documents
+// are not replaced.
+func (s *Server) write(r *request, name, doctype, id string, create bool) (int, interface{}, error) {
+	var source map[string]interface{}
+	if err := r.decode(&source); err != nil {
+		return 0, nil, err
+	}
+	if source == nil {
+		return 0, nil, &esError{status: http.StatusBadRequest, errType: "mapper_parsing_exception", reason: "failed to parse"}
+	}
+	ix, err := s.writableIndex(name, doctype)
+	if err != nil {
+		return 0, nil, err
+	}
+	existing, exists := ix.docs[id]
+	if exists && create {
+		return 0, nil, versionConflict(doctype, id, "document already exists (current version [%d])", existing.version)
+	}
+	version, err := s.checkConcurrency(r, doctype, id, existing, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	doc, created := ix.put(id, source)
+	if version > 0 {
+		doc.version = version
+	}
+	return writeResult(s.meta(ix, doc), created, "")
+}
+
+// writeResult returns the response of a write with the result created or updated,
+// unless the result is given.
+func writeResult(meta map[string]interface{}, created bool, result string) (int, interface{}, error) {
+	status := http.StatusOK
+	switch {
+	case result != "":
+	case created:
+		result, status = "created", http.StatusCreated
+	default:
+		result = "updated"
+	}
+	meta["result"] = result
+	for key, value := range shards(1) {
+		meta[key] = value
+	}
+	return status, meta, nil
+}
+
+func (s *Server) get(r *request, name, id string) (int, interface{}, error) {
+	ix, ok := s.indices[name]
+	if !ok {
+		return 0, nil, indexNotFound(name)
+	}
+	doc, ok := ix.docs[id]
+	if !ok {
+		result := map[string]interface{}{"_index": name, "_id": id, "found": false}
+		if doctype := s.doctype(ix); doctype != "" {
+			result["_type"] = doctype
+		}
+		return http.StatusNotFound, result, nil
+	}
+	result := s.readMeta(ix, doc)
+	result["found"] = true
+	includes, excludes, enabled := sourceFilterFromQuery(r)
+	if enabled {
+		result["_source"] = filterSource(doc.source, includes, excludes)
+	}
+	return http.StatusOK, result, nil
+}
+
+// mget handles the _mget api. The documents are returned in the order of the request.
+func (s *Server) mget(r *request, name string) (int, interface{}, error) {
+	if r.method != http.MethodGet && r.method != http.MethodPost {
+		return 0, nil, badRequest("method [%s] is not allowed", r.method)
+	}
+	type mgetDoc struct {
+		Index  string      `json:"_index"`
+		ID     string      `json:"_id"`
+		Source interface{} `json:"_source"`
+	}
+	body := struct {
+		Docs []mgetDoc `json:"docs"`
+		IDs  []string  `json:"ids"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return 0, nil, err
+	}
+	for _, id := range body.IDs {
+		body.Docs = append(body.Docs, mgetDoc{ID: id})
+	}
+	if len(body.Docs) == 0 {
+		return 0, nil, badRequest("Validation Failed: 1: no documents to get;")
+	}
+	queryIncludes, queryExcludes, queryEnabled := sourceFilterFromQuery(r)
+	docs := make([]interface{}, 0, len(body.Docs))
+	for i, item := range body.Docs {
+		if item.Index == "" {
+			item.Index = name
+		}
+		if item.Index == "" {
+			return 0, nil, badRequest("Validation Failed: 1: index is missing for doc %d;", i)
+		}
+		ix, ok := s.indices[item.Index]
+		if !ok {
+			docs = append(docs, map[string]interface{}{"_index": item.Index, "_id": item.ID, "error": indexNotFound(item.Index).cause()})
+			continue
+		}
+		doc, ok := ix.docs[item.ID]
+		if !ok {
+			result := map[string]interface{}{"_index": item.Index, "_id": item.ID, "found": false}
+			if doctype := s.doctype(ix); doctype != "" {
+				result["_type"] = doctype
+			}
+			docs = append(docs, result)
+			continue
+		}
+		result := s.readMeta(ix, doc)
+		result["found"] = true
+		includes, excludes, enabled := queryIncludes, queryExcludes, queryEnabled
+		if item.Source != nil {
+			includes, excludes, enabled = sourceFilter(item.Source)
+		}
+		if enabled {
+			result["_source"] = filterSource(doc.source, includes, excludes)
+		}
+		docs = append(docs, result)
+	}
+	return http.StatusOK, map[string]interface{}{"docs": docs}, nil
+}
+
+func (s *Server) delete(r *request, name, doctype, id string) (int, interface{}, error) {
+	ix, ok := s.indices[name]
+	if !ok {
+		return 0, nil, indexNotFound(name)
+	}
+	version, err := s.checkConcurrency(r, doctype, id, ix.docs[id], true)
+	if err != nil {
+		return 0, nil, err
+	}
+	doc, ok := ix.remove(id)
+	if version > 0 && ok {
+		doc.version = version
+	}
+	if !ok {
+		result := map[string]interface{}{"_index": name, "_id": id, "result": "not_found"}
+		if doctype := s.doctype(ix); doctype != "" {
+			result["_type"] = doctype
+		}
+		return http.StatusNotFound, result, nil
+	}
+	status, result, err := writeResult(s.meta(ix, doc), false, "deleted")
+	return status, result, err
+}
+
+// update applies a partial document or a script to a document.
+func (s *Server) update(r *request, name, doctype, id string) (int, interface{}, error) {
+	var body map[string]interface{}
+	if err := r.decode(&body); err != nil {
+		return 0, nil, err
+	}
+	ix, err := s.writableIndex(name, doctype)
+	if err != nil {
+		return 0, nil, err
+	}
+	doc, exists := ix.docs[id]
+	if _, err := s.checkConcurrency(r, doctype, id, doc, false); err != nil {
+		return 0, nil, err
+	}
+	result, err := s.applyUpdate(body, doc, exists)
+	if err != nil {
+		if e, ok := err.(*esError); ok && e.errType == "document_missing_exception" {
+			e.reason = "[" + doctype + "][" + id + "]: document missing"
+			e.index = name
+		}
+		return 0, nil, err
+	}
+	switch result.op {
+	case "noop":
+		return writeResult(s.meta(ix, doc), false, "noop")
+	case "delete":
+		doc, _ = ix.remove(id)
+		return writeResult(s.meta(ix, doc), false, "deleted")
+	}
+	doc, created := ix.put(id, result.source)
+	return writeResult(s.meta(ix, doc), created, "")
+}
+
+// updateResult is the new source of a document and the operation of the update.
+type updateResult struct {
+	source map[string]interface{}
+	// op is index, noop or delete
+	op string
+}
+
+// applyUpdate applies the body of an update request to the document, which is
+// nil, if it does not exist.
+func (s *Server) applyUpdate(body map[string]interface{}, doc *document, exists bool) (*updateResult, error) {
+	partial, _ := body["doc"].(map[string]interface{})
+	script, hasScript := body["script"]
+	if partial == nil && !hasScript {
+		return nil, badRequest("Validation Failed: 1: script or doc is missing;")
+	}
+	if !exists {
+		upsert, hasUpsert := body["upsert"].(map[string]interface{})
+		switch {
+		case partial != nil && body["doc_as_upsert"] == true:
+			return &updateResult{source: partial, op: "index"}, nil
+		case hasUpsert && hasScript && body["scripted_upsert"] == true:
+			source := deepCopy(upsert).(map[string]interface{})
+			op, err := s.runScript(script, source)
+			return &updateResult{source: source, op: op}, err
+		case hasUpsert:
+			return &updateResult{source: upsert, op: "index"}, nil
+		}
+		return nil, &esError{status: http.StatusNotFound, errType: "document_missing_exception"}
+	}
+	source := deepCopy(doc.source).(map[string]interface{})
+	if hasScript {
+		op, err := s.runScript(script, source)
+		return &updateResult{source: source, op: op}, err
+	}
+	changed := merge(source, partial)
+	if !changed && body["detect_noop"] != false {
+		return &updateResult{source: source, op: "noop"}, nil
+	}
+	return &updateResult{source: source, op: "index"}, nil
+}
+
+// merge merges the partial document into the source like Elasticsearch does for
+// partial updates and returns true, if the source was changed.
+func merge(source, partial map[string]interface{}) bool {
+	var changed bool
+	for key, value := range partial {
+		existing, ok := source[key].(map[string]interface{})
+		if update, isMap := value.(map[string]interface{}); ok && isMap {
+			changed = merge(existing, update) || changed
+			continue
+		}
+		if old, ok := source[key]; !ok || !equalJSON(old, value) {
+			changed = true
+		}
+		source[key] = deepCopy(value)
+	}
+	return changed
+}
+
+// deepCopy copies maps and slices of decoded json.
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			result[key] = deepCopy(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = deepCopy(value)
+		}
+		return result
+	}
+	return v
+}
+
+func versionConflict(doctype, id string, format string, a ...interface{}) *esError {
+	if doctype == "" {
+		doctype = "_doc"
+	}
+	e := badRequest(format, a...)
+	e.status = http.StatusConflict
+	e.errType = "version_conflict_engine_exception"
+	e.reason = "[" + doctype + "][" + id + "]: version conflict, " + e.reason
+	return e
+}
+
+// checkConcurrency checks the parameters if_seq_no, if_primary_term, version and
+// version_type of a write against the document, which is nil, if it does not exist.
+// It returns the external version for the document or 0, if the version is incremented.
+func (s *Server) checkConcurrency(r *request, doctype, id string, doc *document, allowExternal bool) (int64, error) {
+	params := map[string]int64{}
+	for _, name := range []string{"if_seq_no", "if_primary_term", "version"} {
+		value := r.query.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, badRequest("Failed to parse long parameter [%s] with value [%s]", name, value)
+		}
+		params[name] = n
+	}
+	seqNo, hasSeqNo := params["if_seq_no"]
+	primaryTerm, hasPrimaryTerm := params["if_primary_term"]
+	if (hasSeqNo || hasPrimaryTerm) && !s.seqNoPrimaryTerm() {
+		return 0, badRequest("request contains unrecognized parameters: [if_primary_term], [if_seq_no]")
+	}
+	if hasSeqNo != hasPrimaryTerm {
+		return 0, badRequest("Validation Failed: 1: ifSeqNo is set, but primary term is [0];")
+	}
+	if hasSeqNo {
+		if doc == nil {
+			return 0, versionConflict(doctype, id, "required seqNo [%d], primary term [%d] but no document was found", seqNo, primaryTerm)
+		}
+		if doc.seqNo != seqNo || doc.primaryTerm != primaryTerm {
+			return 0, versionConflict(doctype, id, "required seqNo [%d], primary term [%d]. current document has seqNo [%d] and primary term [%d]", seqNo, primaryTerm, doc.seqNo, doc.primaryTerm)
+		}
+	}
+	version, hasVersion := params["version"]
+	versionType := r.query.Get("version_type")
+	if !hasVersion {
+		if versionType != "" && versionType != "internal" {
+			return 0, badRequest("Validation Failed: 1: an explicit version is required for version type [%s];", versionType)
+		}
+		return 0, nil
+	}
+	var current int64
+	if doc != nil {
+		current = doc.version
+	}
+	switch versionType {
+	case "", "internal":
+		if s.typeless() {
+			return 0, badRequest("internal versioning can not be used for optimistic concurrency control. Please use `if_seq_no` and `if_primary_term` instead")
+		}
+		if doc == nil || current != version {
+			return 0, versionConflict(doctype, id, "current version [%d] is different than the one provided [%d]", current, version)
+		}
+		return 0, nil
+	case "external", "external_gt", "external_gte":
+		if !allowExternal {
+			return 0, badRequest("Validation Failed: 1: version type [%s] is not supported by the update API;", versionType)
+		}
+		if doc != nil && versionType == "external_gte" && current > version {
+			return 0, versionConflict(doctype, id, "current version [%d] is higher than the one provided [%d]", current, version)
+		}
+		if doc != nil && versionType != "external_gte" && current >= version {
+			return 0, versionConflict(doctype, id, "current version [%d] is higher or equal to the one provided [%d]", current, version)
+		}
+		return version, nil
+	}
+	return 0, badRequest("No version type match [%s]", versionType)
+}
+
+// generateID returns a random id like the ids generated by Elasticsearch.
+func generateID() string {
+	b := make([]byte, 15)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,607 @@
+package estest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// matches returns true, if the document matches the query.
+func matches(query map[string]interface{}, doc *document) (bool, error) {
+	if len(query) == 0 {
+		return true, nil
+	}
+	if len(query) != 1 {
+		return false, parsingError("query malformed, must start with start_object")
+	}
+	for queryType, body := range query {
+		switch queryType {
+		case "match_all":
+			return true, nil
+		case "match_none":
+			return false, nil
+		case "bool":
+			clauses, ok := body.(map[string]interface{})
+			if !ok {
+				return false, parsingError("[bool] query malformed")
+			}
+			return matchesBool(clauses, doc)
+		case "ids":
+			clause, _ := body.(map[string]interface{})
+			values, _ := clause["values"].([]interface{})
+			for _, value := range values {
+				if value == doc.id {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "exists":
+			clause, _ := body.(map[string]interface{})
+			field, _ := clause["field"].(string)
+			return len(fieldValues(doc, field)) > 0, nil
+		}
+		field, clause, err := fieldClause(queryType, body)
+		if err != nil {
+			return false, err
+		}
+		values := fieldValues(doc, field)
+		switch queryType {
+		case "term", "match", "match_phrase", "prefix", "wildcard":
+			if params, ok := clause.(map[string]interface{}); ok {
+				for _, key := range []string{"value", "query"} {
+					if value, ok := params[key]; ok {
+						clause = value
+					}
+				}
+			}
+			for _, value := range values {
+				if matchesValue(queryType, value, clause) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "terms":
+			terms, ok := clause.([]interface{})
+			if !ok {
+				return false, parsingError("[terms] query does not support [%s]", field)
+			}
+			for _, value := range values {
+				for _, term := range terms {
+					if equalValues(value, term) {
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		case "range":
+			bounds, ok := clause.(map[string]interface{})
+			if !ok {
+				return false, parsingError("[range] query malformed, no start_object after query name")
+			}
+			for _, value := range values {
+				if inRange(value, bounds) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return false, parsingError("no [query] registered for [%s]", queryType)
+	}
+	return false, nil
+}
+
+// fieldClause returns the field and its clause of queries like {"term": {"field": "value"}}.
+func fieldClause(queryType string, body interface{}) (string, interface{}, error) {
+	clause, ok := body.(map[string]interface{})
+	if !ok {
+		return "", nil, parsingError("[%s] query malformed, no start_object after query name", queryType)
+	}
+	var field string
+	var value interface{}
+	for key, v := range clause {
+		if key == "boost" || key == "_name" {
+			continue
+		}
+		if field != "" {
+			return "", nil, parsingError("[%s] query doesn't support multiple fields, found [%s] and [%s]", queryType, field, key)
+		}
+		field, value = key, v
+	}
+	if field == "" {
+		return "", nil, parsingError("[%s] query requires a field", queryType)
+	}
+	return field, value, nil
+}
+
+func matchesBool(clauses map[string]interface{}, doc *document) (bool, error) {
+	var must, should, mustNot []map[string]interface{}
+	for key, value := range clauses {
+		queries, err := queryList(key, value)
+		if err != nil {
+			return false, err
+		}
+		switch key {
+		case "must", "filter":
+			must = append(must, queries...)
+		case "should":
+			should = queries
+		case "must_not":
+			mustNot = queries
+		case "minimum_should_match", "boost", "_name":
+		default:
+			return false, parsingError("[bool] query does not support [%s]", key)
+		}
+	}
+	for _, query := range must {
+		if ok, err := matches(query, doc); !ok || err != nil {
+			return false, err
+		}
+	}
+	for _, query := range mustNot {
+		if ok, err := matches(query, doc); ok || err != nil {
+			return false, err
+		}
+	}
+	minimumShouldMatch := 0
+	if len(must) == 0 && len(should) > 0 {
+		minimumShouldMatch = 1
+	}
+	if value, ok := clauses["minimum_should_match"]; ok {
+		n, err := strconv.Atoi(fmt.Sprint(value))
+		if err != nil {
+			return false, parsingError("[bool] query only supports numbers as minimum_should_match")
+		}
+		minimumShouldMatch = n
+	}
+	var matched int
+	for _, query := range should {
+		ok, err := matches(query, doc)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched >= minimumShouldMatch, nil
+}
+
+// queryList returns the queries of a bool clause, which is a single query or a list.
+func queryList(key string, value interface{}) ([]map[string]interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{value}, nil
+	case []interface{}:
+		var result []map[string]interface{}
+		for _, query := range value {
+			q, ok := query.(map[string]interface{})
+			if !ok {
+				return nil, parsingError("[bool] query malformed in [%s]", key)
+			}
+			result = append(result, q)
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+// matchesValue compares a value of a document with the value of a term, match,
+// prefix or wildcard query.
+func matchesValue(queryType string, value, query interface{}) bool {
+	switch queryType {
+	case "match", "match_phrase":
+		s, ok := value.(string)
+		q, isString := query.(string)
+		if !ok || !isString {
+			return equalValues(value, query)
+		}
+		text := " " + strings.Join(strings.Fields(strings.ToLower(s)), " ") + " "
+		if queryType == "match_phrase" {
+			return strings.Contains(text, " "+strings.Join(strings.Fields(strings.ToLower(q)), " ")+" ")
+		}
+		for _, token := range strings.Fields(strings.ToLower(q)) {
+			if strings.Contains(text, " "+token+" ") {
+				return true
+			}
+		}
+		return false
+	case "prefix":
+		return strings.HasPrefix(fmt.Sprint(value), fmt.Sprint(query))
+	case "wildcard":
+		ok, _ := path.Match(fmt.Sprint(query), fmt.Sprint(value))
+		return ok
+	}
+	return equalValues(value, query)
+}
+
+func inRange(value interface{}, bounds map[string]interface{}) bool {
+	for op, bound := range bounds {
+		switch op {
+		case "gt", "gte", "lt", "lte", "from", "to":
+		default:
+			continue
+		}
+		if bound == nil {
+			continue
+		}
+		c, ok := compareValues(value, bound)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "gt":
+			ok = c > 0
+		case "gte", "from":
+			ok = c >= 0
+		case "lt":
+			ok = c < 0
+		case "lte", "to":
+			ok = c <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parsingError(format string, a ...interface{}) *esError {
+	e := badRequest(format, a...)
+	e.errType = "parsing_exception"
+	return e
+}
+
+// fieldValues returns all values of a field in the document. Fields of objects
+// are addressed with dots, values of arrays are flattened. If the field does not
+// exist, the .keyword suffix of dynamic mappings is removed.
+func fieldValues(doc *document, field string) []interface{} {
+	switch field {
+	case "_id":
+		return []interface{}{doc.id}
+	case "_index":
+		return []interface{}{doc.index}
+	}
+	values := collect(doc.source, strings.Split(field, "."))
+	if len(values) == 0 && strings.HasSuffix(field, ".keyword") {
+		values = collect(doc.source, strings.Split(strings.TrimSuffix(field, ".keyword"), "."))
+	}
+	return values
+}
+
+func collect(v interface{}, fields []string) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		var result []interface{}
+		for _, element := range v {
+			result = append(result, collect(element, fields)...)
+		}
+		return result
+	case map[string]interface{}:
+		if len(fields) == 0 {
+			return []interface{}{v}
+		}
+		// fields may contain dots as well
+		for i := len(fields); i > 0; i-- {
+			if value, ok := v[strings.Join(fields[:i], ".")]; ok {
+				return collect(value, fields[i:])
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	}
+	if len(fields) > 0 {
+		return nil
+	}
+	return []interface{}{v}
+}
+
+// dateLayouts are the date formats understood by range queries and date histograms.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses a date string in one of the dateLayouts.
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// numeric returns the number of a value. Date strings are converted to
+// milliseconds since the epoch like Elasticsearch does for date fields.
+func numeric(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+		if t, ok := parseDate(v); ok {
+			return float64(t.UnixNano() / int64(time.Millisecond)), true
+		}
+	}
+	return 0, false
+}
+
+// compareValues compares numbers, dates and strings. It returns false, if the
+// values are not comparable.
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := numeric(a); ok {
+		if y, ok := numeric(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	x, ok1 := a.(string)
+	y, ok2 := b.(string)
+	if !ok1 || !ok2 {
+		if a == nil || b == nil {
+			return 0, false
+		}
+		x, y = fmt.Sprint(a), fmt.Sprint(b)
+	}
+	return strings.Compare(x, y), true
+}
+
+// equalValues returns true, if both values are equal. Numbers are compared by
+// their value, all other values by their string representation.
+func equalValues(a, b interface{}) bool {
+	_, aString := a.(string)
+	_, bString := b.(string)
+	if !aString || !bString {
+		if x, ok := numeric(a); ok {
+			if y, ok := numeric(b); ok {
+				return x == y
+			}
+		}
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// equalJSON returns true, if both decoded json values are equal.
+func equalJSON(a, b interface{}) bool {
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(x) == string(y)
+}
+
+// sourceFilterFromQuery returns the source filter of the _source, _source_includes
+// and _source_excludes query parameters.
+func sourceFilterFromQuery(r *request) (includes, excludes []string, enabled bool) {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, ",")
+	}
+	enabled = true
+	switch value := r.query.Get("_source"); value {
+	case "false":
+		enabled = false
+	case "true", "":
+	default:
+		includes = split(value)
+	}
+	if value := r.query.Get("_source_includes"); value != "" {
+		includes = split(value)
+	}
+	if value := r.query.Get("_source_excludes"); value != "" {
+		excludes = split(value)
+	}
+	return includes, excludes, enabled
+}
+
+// sourceFilter returns the source filter of a _source field in a request body,
+// which is a boolean, a pattern, a list of patterns or an object with includes
+// and excludes.
+func sourceFilter(v interface{}) (includes, excludes []string, enabled bool) {
+	patterns := func(v interface{}) []string {
+		switch v := v.(type) {
+		case string:
+			return []string{v}
+		case []interface{}:
+			var result []string
+			for _, pattern := range v {
+				result = append(result, fmt.Sprint(pattern))
+			}
+			return result
+		}
+		return nil
+	}
+	switch v := v.(type) {
+	case bool:
+		return nil, nil, v
+	case map[string]interface{}:
+		includes = patterns(v["includes"])
+		if includes == nil {
+			includes = patterns(v["include"])
+		}
+		excludes = patterns(v["excludes"])
+		if excludes == nil {
+			excludes = patterns(v["exclude"])
+		}
+		return includes, excludes, true
+	}
+	return patterns(v), nil, true
+}
+
+// filterSource returns the source with the included fields and without the
+// excluded fields. Patterns may contain wildcards and address nested fields
+// with dots.
+func filterSource(source map[string]interface{}, includes, excludes []string) map[string]interface{} {
+	if len(includes) == 0 && len(excludes) == 0 {
+		return source
+	}
+	return filterObject(source, "", includes, excludes)
+}
+
+func filterObject(object map[string]interface{}, prefix string, includes, excludes []string) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range object {
+		field := prefix + key
+		if matchesAny(excludes, field) {
+			continue
+		}
+		if len(includes) == 0 || matchesAny(includes, field) {
+			if child, ok := value.(map[string]interface{}); ok && len(excludes) > 0 {
+				value = filterObject(child, field+".", nil, excludes)
+			}
+			result[key] = value
+			continue
+		}
+		if child, ok := value.(map[string]interface{}); ok && hasPrefix(includes, field+".") {
+			if filtered := filterObject(child, field+".", includes, excludes); len(filtered) > 0 {
+				result[key] = filtered
+			}
+		}
+	}
+	return result
+}
+
+func matchesAny(patterns []string, field string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, field); ok || strings.HasPrefix(field, pattern+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefix returns true, if a pattern could match a field below the prefix.
+func hasPrefix(patterns []string, prefix string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, prefix) || strings.HasPrefix(pattern, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// sortField is a single field of the sort of a search request.
+type sortField struct {
+	field string
+	desc  bool
+}
+
+// parseSort parses the sort of a search request, which is a field, an object
+// or a list of both.
+func parseSort(v interface{}) ([]sortField, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if i := strings.LastIndex(v, ":"); i >= 0 {
+			return []sortField{{field: v[:i], desc: v[i+1:] == "desc"}}, nil
+		}
+		return []sortField{{field: v, desc: v == "_score"}}, nil
+	case map[string]interface{}:
+		var result []sortField
+		for _, field := range sortedKeys(v) {
+			order := v[field]
+			if params, ok := order.(map[string]interface{}); ok {
+				order = params["order"]
+			}
+			switch order {
+			case "asc", "desc", nil:
+			default:
+				return nil, parsingError("[sort] unknown order [%v] for field [%s]", order, field)
+			}
+			result = append(result, sortField{field: field, desc: order == "desc"})
+		}
+		return result, nil
+	case []interface{}:
+		var result []sortField
+		for _, element := range v {
+			fields, err := parseSort(element)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, fields...)
+		}
+		return result, nil
+	}
+	return nil, parsingError("[sort] malformed")
+}
+
+// sortValue returns the value of the document used for sorting.
+func (f sortField) value(doc *document) interface{} {
+	switch f.field {
+	case "_doc":
+		return doc.position
+	case "_score":
+		return 1.0
+	}
+	values := fieldValues(doc, f.field)
+	if len(values) == 0 {
+		return nil
+	}
+	result := values[0]
+	for _, value := range values[1:] {
+		if c, ok := compareValues(value, result); ok && (c < 0) != f.desc {
+			result = value
+		}
+	}
+	return result
+}
+
+// less compares two documents by the sort fields. Missing values are sorted last.
+func less(fields []sortField, a, b *document) bool {
+	for _, field := range fields {
+		x, y := field.value(a), field.value(b)
+		switch {
+		case x == nil && y == nil:
+			continue
+		case x == nil:
+			return false
+		case y == nil:
+			return true
+		}
+		c, _ := compareValues(x, y)
+		if c != 0 {
+			return (c < 0) != field.desc
+		}
+	}
+	return false
+}
+
+// afterSearchAfter returns true, if the sort values of the document are after the
+// search_after values.
+func afterSearchAfter(fields []sortField, doc *document, after []interface{}) bool {
+	for i, field := range fields {
+		if i >= len(after) {
+			break
+		}
+		value := field.value(doc)
+		if value == nil {
+			return true
+		}
+		c, _ := compareValues(value, after[i])
+		if c != 0 {
+			return (c > 0) != field.desc
+		}
+	}
+	return false
+}
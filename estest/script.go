@@ -0,0 +1,148 @@
+package estest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// script statements understood without registration
+var (
+	assignStatement = regexp.MustCompile(`^ctx\._source\.([\w.]+)\s*(=|\+=|-=)\s*(.+)$`)
+	removeStatement = regexp.MustCompile(`^ctx\._source(?:\.([\w.]+))?\.remove\(\s*['"]([^'"]+)['"]\s*\)$`)
+	addStatement    = regexp.MustCompile(`^ctx\._source\.([\w.]+)\.add\((.+)\)$`)
+	opStatement     = regexp.MustCompile(`^ctx\.op\s*=\s*['"](\w+)['"]$`)
+	bracketAccess   = regexp.MustCompile(`\[\s*['"]([^'"]+)['"]\s*\]`)
+)
+
+// runScript runs an update script on the source and returns the operation set by
+// the script, which is index, noop or delete.
+func (s *Server) runScript(script interface{}, source map[string]interface{}) (string, error) {
+	var code string
+	params := map[string]interface{}{}
+	switch script := script.(type) {
+	case string:
+		code = script
+	case map[string]interface{}:
+		for _, key := range []string{"source", "inline"} {
+			if value, ok := script[key].(string); ok {
+				code = value
+			}
+		}
+		if value, ok := script["params"].(map[string]interface{}); ok {
+			params = value
+		}
+		if id, ok := script["id"].(string); ok && code == "" {
+			code = id
+		}
+	}
+	if f, ok := s.scripts[code]; ok {
+		if err := f(source, params); err != nil {
+			return "", scriptError(code, err.Error())
+		}
+		return "index", nil
+	}
+	op := "index"
+	for _, statement := range strings.Split(code, ";") {
+		statement = strings.TrimSpace(bracketAccess.ReplaceAllString(statement, ".$1"))
+		if statement == "" {
+			continue
+		}
+		if match := opStatement.FindStringSubmatch(statement); match != nil {
+			op = match[1]
+			continue
+		}
+		if match := removeStatement.FindStringSubmatch(statement); match != nil {
+			object := source
+			if match[1] != "" {
+				object, _ = lookup(source, match[1]).(map[string]interface{})
+			}
+			if object != nil {
+				delete(object, match[2])
+			}
+			continue
+		}
+		if match := addStatement.FindStringSubmatch(statement); match != nil {
+			value, err := evaluate(match[2], params)
+			if err != nil {
+				return "", scriptError(code, err.Error())
+			}
+			list, _ := lookup(source, match[1]).([]interface{})
+			assign(source, match[1], append(list, value))
+			continue
+		}
+		match := assignStatement.FindStringSubmatch(statement)
+		if match == nil {
+			return "", scriptError(code, "estest does not support the statement ["+statement+"], register the script with HandleScript")
+		}
+		value, err := evaluate(match[3], params)
+		if err != nil {
+			return "", scriptError(code, err.Error())
+		}
+		if match[2] != "=" {
+			x, ok1 := numeric(lookup(source, match[1]))
+			y, ok2 := numeric(value)
+			if !ok1 || !ok2 {
+				return "", scriptError(code, "cannot apply ["+match[2]+"] to ["+match[1]+"]")
+			}
+			if match[2] == "-=" {
+				y = -y
+			}
+			value = json.Number(fmt.Sprint(x + y))
+		}
+		assign(source, match[1], value)
+	}
+	return op, nil
+}
+
+// evaluate returns the value of a params reference or a literal.
+func evaluate(expression string, params map[string]interface{}) (interface{}, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "params.") {
+		value := lookup(params, strings.TrimPrefix(expression, "params."))
+		return deepCopy(value), nil
+	}
+	if len(expression) >= 2 && expression[0] == '\'' && expression[len(expression)-1] == '\'' {
+		expression = `"` + strings.Replace(expression[1:len(expression)-1], `"`, `\"`, -1) + `"`
+	}
+	var value interface{}
+	if err := decodeJSON([]byte(expression), &value); err != nil {
+		return nil, fmt.Errorf("estest does not support the expression [%s]", expression)
+	}
+	return value, nil
+}
+
+// lookup returns the value of a dotted field in the object.
+func lookup(object map[string]interface{}, field string) interface{} {
+	var value interface{} = object
+	for _, key := range strings.Split(field, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// assign sets a dotted field in the object and creates missing objects.
+func assign(object map[string]interface{}, field string, value interface{}) {
+	keys := strings.Split(field, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			object[key] = child
+		}
+		object = child
+	}
+	object[keys[len(keys)-1]] = value
+}
+
+func scriptError(code, reason string) *esError {
+	e := badRequest("%s", reason)
+	e.errType = "script_exception"
+	e.reason = "runtime error in script [" + code + "]: " + reason
+	return e
+}
@@ -0,0 +1,372 @@
+package estest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// maxResultWindow is the default of index.max_result_window.
+const maxResultWindow = 10000
+
+// searchRequest is the body of a search request.
+type searchRequest struct {
+	Query            map[string]interface{} `json:"query"`
+	From             *int                   `json:"from"`
+	Size             *int                   `json:"size"`
+	Sort             interface{}            `json:"sort"`
+	Source           interface{}            `json:"_source"`
+	SearchAfter      []interface{}          `json:"search_after"`
+	Version          bool                   `json:"version"`
+	SeqNoPrimaryTerm bool                   `json:"seq_no_primary_term"`
+	Aggs             map[string]interface{} `json:"aggs"`
+	Aggregations     map[string]interface{} `json:"aggregations"`
+	Script           interface{}            `json:"script"`
+}
+
+// parseSearch parses the body and the query parameters of a search request.
+func parseSearch(r *request) (*searchRequest, error) {
+	search := &searchRequest{}
+	if err := r.decode(search); err != nil {
+		return nil, err
+	}
+	for _, param := range []struct {
+		name  string
+		value **int
+	}{{"from", &search.From}, {"size", &search.Size}} {
+		if value := r.query.Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, badRequest("Failed to parse int parameter [%s] with value [%s]", param.name, value)
+			}
+			*param.value = &n
+		}
+	}
+	if sort := r.query.Get("sort"); sort != "" {
+		search.Sort = sort
+	}
+	if r.bool("version") {
+		search.Version = true
+	}
+	if r.bool("seq_no_primary_term") {
+		search.SeqNoPrimaryTerm = true
+	}
+	if search.Aggs == nil {
+		search.Aggs = search.Aggregations
+	}
+	return search, nil
+}
+
+// find returns all documents of the indices matching the query.
+func (s *Server) find(names string, query map[string]interface{}) ([]*index, []*document, error) {
+	indices, err := s.resolve(names)
+	if err != nil {
+		return nil, nil, err
+	}
+	var result []*document
+	for _, ix := range indices {
+		for _, doc := range ix.documents() {
+			ok, err := matches(query, doc)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				result = append(result, doc)
+			}
+		}
+	}
+	return indices, result, nil
+}
+
+func (s *Server) search(r *request, names string) (int, interface{}, error) {
+	search, err := parseSearch(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	indices, docs, err := s.find(names, search.Query)
+	if err != nil {
+		return 0, nil, err
+	}
+	sortFields, err := parseSort(search.Sort)
+	if err != nil {
+		return 0, nil, err
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return less(sortFields, docs[i], docs[j])
+	})
+	total := len(docs)
+	if search.SearchAfter != nil {
+		var after []*document
+		for _, doc := range docs {
+			if afterSearchAfter(sortFields, doc, search.SearchAfter) {
+				after = append(after, doc)
+			}
+		}
+		docs = after
+	}
+	from, size := 0, 10
+	if search.From != nil {
+		from = *search.From
+	}
+	if search.Size != nil {
+		size = *search.Size
+	}
+	scrolling := r.query.Get("scroll") != ""
+	if !scrolling && from+size > maxResultWindow {
+		return 0, nil, badRequest("Result window is too large, from + size must be less than or equal to: [%d] but was [%d].", maxResultWindow, from+size)
+	}
+	result := map[string]interface{}{
+		"took":      1,
+		"timed_out": false,
+	}
+	for key, value := range shards(len(indices)) {
+		result[key] = value
+	}
+	hits := s.hits(docs, search, sortFields)
+	if search.Aggs != nil {
+		aggregations, err := aggregate(search.Aggs, docs)
+		if err != nil {
+			return 0, nil, err
+		}
+		result["aggregations"] = aggregations
+	}
+	if scrolling {
+		s.scrollID++
+		id := "estest-scroll-" + strconv.Itoa(s.scrollID)
+		s.scrolls[id] = &scroll{hits: hits, size: size, total: total, restTotalHitsAsInt: r.bool("rest_total_hits_as_int")}
+		result["_scroll_id"] = id
+		result["hits"] = s.scrolls[id].next(s)
+		return http.StatusOK, result, nil
+	}
+	if from > len(hits) {
+		from = len(hits)
+	}
+	if from+size < len(hits) {
+		hits = hits[from : from+size]
+	} else {
+		hits = hits[from:]
+	}
+	result["hits"] = s.hitsResult(hits, total, r.bool("rest_total_hits_as_int"), len(sortFields) == 0)
+	return http.StatusOK, result, nil
+}
+
+// hits returns the rendered hits for all documents.
+func (s *Server) hits(docs []*document, search *searchRequest, sortFields []sortField) []interface{} {
+	includes, excludes, enabled := sourceFilter(search.Source)
+	if search.Source == nil {
+		enabled = true
+	}
+	var result []interface{}
+	for _, doc := range docs {
+		ix := s.indices[doc.index]
+		hit := map[string]interface{}{
+			"_index": doc.index,
+			"_id":    doc.id,
+			"_score": 1.0,
+		}
+		if doctype := s.doctype(ix); doctype != "" {
+			hit["_type"] = doctype
+		}
+		if enabled {
+			hit["_source"] = filterSource(doc.source, includes, excludes)
+		}
+		if search.Version {
+			hit["_version"] = doc.version
+		}
+		if search.SeqNoPrimaryTerm {
+			hit["_seq_no"] = doc.seqNo
+			hit["_primary_term"] = doc.primaryTerm
+		}
+		if len(sortFields) > 0 {
+			var values []interface{}
+			for _, field := range sortFields {
+				values = append(values, field.value(doc))
+			}
+			hit["_score"] = nil
+			hit["sort"] = values
+		}
+		result = append(result, hit)
+	}
+	return result
+}
+
+// hitsResult returns the hits object of a search response. Since Elasticsearch 7,
+// the total is an object, unless rest_total_hits_as_int is set.
+func (s *Server) hitsResult(hits []interface{}, total int, restTotalHitsAsInt, scored bool) map[string]interface{} {
+	if hits == nil {
+		hits = []interface{}{}
+	}
+	result := map[string]interface{}{
+		"total":     total,
+		"max_score": nil,
+		"hits":      hits,
+	}
+	if s.typeless() && !restTotalHitsAsInt {
+		result["total"] = map[string]interface{}{"value": total, "relation": "eq"}
+	}
+	if scored && len(hits) > 0 {
+		result["max_score"] = 1.0
+	}
+	return result
+}
+
+func (s *Server) count(r *request, names string) (int, interface{}, error) {
+	search, err := parseSearch(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	indices, docs, err := s.find(names, search.Query)
+	if err != nil {
+		return 0, nil, err
+	}
+	result := shards(len(indices))
+	result["count"] = len(docs)
+	return http.StatusOK, result, nil
+}
+
+// scroll is an open scroll context with all hits of the search.
+type scroll struct {
+	hits               []interface{}
+	position           int
+	size               int
+	total              int
+	restTotalHitsAsInt bool
+}
+
+// next returns the hits object with the next page of hits.
+func (sc *scroll) next(s *Server) map[string]interface{} {
+	end := sc.position + sc.size
+	if end > len(sc.hits) {
+		end = len(sc.hits)
+	}
+	page := sc.hits[sc.position:end]
+	sc.position = end
+	return s.hitsResult(page, sc.total, sc.restTotalHitsAsInt, false)
+}
+
+// scroll handles the _search/scroll api to continue and to clear scrolls.
+func (s *Server) scroll(r *request) (int, interface{}, error) {
+	body := struct {
+		ScrollID interface{} `json:"scroll_id"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return 0, nil, err
+	}
+	var ids []string
+	switch id := body.ScrollID.(type) {
+	case string:
+		ids = []string{id}
+	case []interface{}:
+		for _, element := range id {
+			if s, ok := element.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+	}
+	if len(r.parts) > 2 {
+		ids = r.parts[2:]
+	}
+	if id := r.query.Get("scroll_id"); id != "" {
+		ids = []string{id}
+	}
+	if r.method == http.MethodDelete {
+		var freed int
+		for _, id := range ids {
+			if _, ok := s.scrolls[id]; ok || id == "_all" {
+				freed++
+			}
+			delete(s.scrolls, id)
+			if id == "_all" {
+				s.scrolls = map[string]*scroll{}
+			}
+		}
+		status := http.StatusOK
+		if freed == 0 {
+			status = http.StatusNotFound
+		}
+		return status, map[string]interface{}{"succeeded": true, "num_freed": freed}, nil
+	}
+	if len(ids) != 1 {
+		return 0, nil, badRequest("Validation Failed: 1: scrollId is missing;")
+	}
+	sc, ok := s.scrolls[ids[0]]
+	if !ok {
+		return 0, nil, &esError{status: http.StatusNotFound, errType: "search_context_missing_exception", reason: "No search context found for id [" + ids[0] + "]"}
+	}
+	result := map[string]interface{}{
+		"_scroll_id": ids[0],
+		"took":       1,
+		"timed_out":  false,
+		"hits":       sc.next(s),
+	}
+	for key, value := range shards(1) {
+		result[key] = value
+	}
+	return http.StatusOK, result, nil
+}
+
+// byQueryResult returns the response of _update_by_query and _delete_by_query.
+func byQueryResult(total, updated, deleted, noops int) map[string]interface{} {
+	return map[string]interface{}{
+		"took":              1,
+		"timed_out":         false,
+		"total":             total,
+		"updated":           updated,
+		"deleted":           deleted,
+		"batches":           1,
+		"version_conflicts": 0,
+		"noops":             noops,
+		"failures":          []interface{}{},
+	}
+}
+
+func (s *Server) updateByQuery(r *request, names string) (int, interface{}, error) {
+	search, err := parseSearch(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, docs, err := s.find(names, search.Query)
+	if err != nil {
+		return 0, nil, err
+	}
+	var updated, deleted, noops int
+	for _, doc := range docs {
+		ix := s.indices[doc.index]
+		if search.Script == nil {
+			ix.put(doc.id, doc.source)
+			updated++
+			continue
+		}
+		source := deepCopy(doc.source).(map[string]interface{})
+		op, err := s.runScript(search.Script, source)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case "noop":
+			noops++
+		case "delete":
+			ix.remove(doc.id)
+			deleted++
+		default:
+			ix.put(doc.id, source)
+			updated++
+		}
+	}
+	return http.StatusOK, byQueryResult(len(docs), updated, deleted, noops), nil
+}
+
+func (s *Server) deleteByQuery(r *request, names string) (int, interface{}, error) {
+	search, err := parseSearch(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, docs, err := s.find(names, search.Query)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, doc := range docs {
+		s.indices[doc.index].remove(doc.id)
+	}
+	return http.StatusOK, byQueryResult(len(docs), 0, len(docs), 0), nil
+}
@@ -0,0 +1,404 @@
+// Package estest provides an in-memory fake of Elasticsearch for unit tests.
+//
+// The fake implements the apis used by the elasticsearch package: documents,
+// aliases, _mget, _search with match_all, term, terms, range, exists, ids and bool queries,
+// sorting, scrolling, _bulk, _update_by_query, _delete_by_query, _refresh,
+// _cluster/health, templates and the terms, min, max, sum, avg, value_count,
+// cardinality, composite, date_histogram and auto_date_histogram aggregations.
+// Writes support optimistic concurrency control with if_seq_no, if_primary_term,
+// version and version_type.
+//
+// All changes are visible immediately, as if every request was done with refresh.
+// Mappings are not evaluated, term queries and aggregations compare the values
+// of the documents as they were inserted.
+package estest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultVersion is the version of Elasticsearch emulated by NewServer.
+const DefaultVersion = "6.8.23"
+
+// ScriptFunc modifies the source of a document for an update script. The params of
+// the script are passed in 'params'.
+type ScriptFunc func(source map[string]interface{}, params map[string]interface{}) error
+
+// Server is a fake Elasticsearch node, that keeps all data in memory.
+type Server struct {
+	*httptest.Server
+	version    string
+	major      int
+	minor      int
+	mu         sync.Mutex
+	indices    map[string]*index
+	templates  map[string]interface{}
+	composable map[string]interface{}
+	scrolls    map[string]*scroll
+	scrollID   int
+	scripts    map[string]ScriptFunc
+}
+
+// NewServer starts a fake of Elasticsearch in version DefaultVersion. The caller
+// has to close the server.
+func NewServer() *Server {
+	return NewServerVersion(DefaultVersion)
+}
+
+// NewServerVersion starts a fake of Elasticsearch in the given version, e.g. 7.17.0.
+// The version changes the responses like Elasticsearch does, e.g. the format of
+// the total hits or the _type fields.
+func NewServerVersion(version string) *Server {
+	s := &Server{version: version}
+	parts := strings.SplitN(version, ".", 3)
+	s.major, _ = strconv.Atoi(parts[0])
+	if len(parts) > 1 {
+		s.minor, _ = strconv.Atoi(parts[1])
+	}
+	s.Reset()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Reset deletes all indices, templates and scrolls. Registered scripts are kept.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indices = map[string]*index{}
+	s.templates = map[string]interface{}{}
+	s.composable = map[string]interface{}{}
+	s.scrolls = map[string]*scroll{}
+	if s.scripts == nil {
+		s.scripts = map[string]ScriptFunc{}
+	}
+}
+
+// HandleScript registers the implementation of an update script. The fake only
+// understands simple painless scripts like "ctx._source.field = params.value",
+// all other scripts have to be registered with their exact source.
+func (s *Server) HandleScript(source string, f ScriptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[source] = f
+}
+
+// typeless returns true, if the emulated version has no mapping types.
+func (s *Server) typeless() bool {
+	return s.major >= 7
+}
+
+// seqNoPrimaryTerm returns true, if the emulated version supports if_seq_no and
+// if_primary_term and returns _seq_no and _primary_term for reads.
+func (s *Server) seqNoPrimaryTerm() bool {
+	return s.major > 6 || s.major == 6 && s.minor >= 7
+}
+
+// request contains the parsed http request.
+type request struct {
+	method string
+	parts  []string
+	query  url.Values
+	body   []byte
+}
+
+// bool returns true, if the query parameter is set to true or is set without value.
+func (r *request) bool(name string) bool {
+	value, ok := r.query[name]
+	return ok && (value[0] == "" || value[0] == "true")
+}
+
+// decode decodes the json body into v. Numbers are decoded as json.Number to
+// return them unchanged.
+func (r *request) decode(v interface{}) error {
+	if len(bytes.TrimSpace(r.body)) == 0 {
+		return nil
+	}
+	return decodeJSON(r.body, v)
+}
+
+func decodeJSON(b []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return &esError{status: http.StatusBadRequest, errType: "parse_exception", reason: fmt.Sprintf("failed to parse body: %s", err)}
+	}
+	return nil
+}
+
+// esError is an error response in the format of Elasticsearch.
+type esError struct {
+	status  int
+	errType string
+	reason  string
+	index   string
+}
+
+func (e *esError) Error() string {
+	return fmt.Sprintf("%s: %s", e.errType, e.reason)
+}
+
+func (e *esError) cause() map[string]interface{} {
+	result := map[string]interface{}{
+		"type":   e.errType,
+		"reason": e.reason,
+	}
+	if e.index != "" {
+		result["index"] = e.index
+	}
+	return result
+}
+
+func indexNotFound(name string) *esError {
+	return &esError{status: http.StatusNotFound, errType: "index_not_found_exception", reason: "no such index [" + name + "]", index: name}
+}
+
+func badRequest(format string, a ...interface{}) *esError {
+	return &esError{status: http.StatusBadRequest, errType: "illegal_argument_exception", reason: fmt.Sprintf(format, a...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.major > 7 || s.major == 7 && s.minor >= 14 {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeError(w, badRequest("could not decompress body: %s", err))
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		writeError(w, badRequest("could not read body: %s", err))
+		return
+	}
+	req := &request{method: r.Method, query: r.URL.Query(), body: b}
+	for _, part := range strings.Split(strings.Trim(r.URL.Path, "/"), "/") {
+		if part != "" {
+			req.parts = append(req.parts, part)
+		}
+	}
+	s.mu.Lock()
+	status, result, err := s.route(req)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status, b = http.StatusInternalServerError, []byte(`{"error":"could not marshal response"}`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*esError)
+	if !ok {
+		e = &esError{status: http.StatusInternalServerError, errType: "exception", reason: err.Error()}
+	}
+	writeJSON(w, e.status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"root_cause": []interface{}{e.cause()},
+			"type":       e.errType,
+			"reason":     e.reason,
+			"index":      e.index,
+		},
+		"status": e.status,
+	})
+}
+
+// route dispatches the request to the handler of the api.
+func (s *Server) route(r *request) (int, interface{}, error) {
+	parts := r.parts
+	switch {
+	case len(parts) == 0:
+		return s.info(r)
+	case parts[0] == "_cluster" && len(parts) >= 2 && parts[1] == "health":
+		return s.health(r)
+	case parts[0] == "_bulk" && len(parts) == 1:
+		return s.bulk(r, "", "")
+	case parts[0] == "_search" && len(parts) == 1:
+		return s.search(r, "_all")
+	case parts[0] == "_search" && parts[1] == "scroll":
+		return s.scroll(r)
+	case parts[0] == "_alias" && len(parts) <= 2:
+		return s.alias(r, "_all", strings.Join(parts[1:], ""))
+	case parts[0] == "_aliases" && len(parts) == 1:
+		return s.aliases(r)
+	case parts[0] == "_mget" && len(parts) == 1:
+		return s.mget(r, "")
+	case parts[0] == "_refresh" && len(parts) == 1:
+		return http.StatusOK, shards(1), nil
+	case (parts[0] == "_template" || parts[0] == "_index_template") && len(parts) == 2:
+		return s.template(r, parts[0] == "_index_template", parts[1])
+	case strings.HasPrefix(parts[0], "_"):
+		break
+	case len(parts) == 1:
+		return s.index(r, parts[0])
+	case len(parts) == 2 && strings.HasPrefix(parts[1], "_") && parts[1] != "_doc":
+		return s.indexAPI(r, parts[0], "", parts[1])
+	case len(parts) == 2:
+		return s.document(r, parts[0], parts[1], "")
+	case len(parts) == 3 && (parts[1] == "_alias" || parts[1] == "_aliases"):
+		return s.alias(r, parts[0], parts[2])
+	case len(parts) == 3 && (parts[1] == "_update" || parts[1] == "_create"):
+		return s.documentAPI(r, parts[0], "_doc", parts[2], parts[1])
+	case len(parts) == 3 && strings.HasPrefix(parts[2], "_"):
+		return s.indexAPI(r, parts[0], parts[1], parts[2])
+	case len(parts) == 3:
+		return s.document(r, parts[0], parts[1], parts[2])
+	case len(parts) == 4 && (parts[3] == "_update" || parts[3] == "_create"):
+		return s.documentAPI(r, parts[0], parts[1], parts[2], parts[3])
+	}
+	return 0, nil, badRequest("no handler found for uri [/%s] and method [%s]", strings.Join(parts, "/"), r.method)
+}
+
+// indexAPI dispatches the apis of an index, like _search or _bulk.
+func (s *Server) indexAPI(r *request, name, doctype, api string) (int, interface{}, error) {
+	switch api {
+	case "_search":
+		return s.search(r, name)
+	case "_count":
+		return s.count(r, name)
+	case "_mget":
+		return s.mget(r, name)
+	case "_alias", "_aliases":
+		return s.alias(r, name, "")
+	case "_bulk":
+		return s.bulk(r, name, doctype)
+	case "_update_by_query":
+		return s.updateByQuery(r, name)
+	case "_delete_by_query":
+		return s.deleteByQuery(r, name)
+	case "_refresh":
+		if _, err := s.resolve(name); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, shards(1), nil
+	}
+	return 0, nil, badRequest("no handler found for uri [/%s/%s] and method [%s]", name, api, r.method)
+}
+
+func (s *Server) info(r *request) (int, interface{}, error) {
+	if r.method != http.MethodGet && r.method != http.MethodHead {
+		return 0, nil, badRequest("method [%s] is not allowed", r.method)
+	}
+	return http.StatusOK, map[string]interface{}{
+		"name":         "estest",
+		"cluster_name": "estest",
+		"cluster_uuid": "ZXN0ZXN0LWNsdXN0ZXI",
+		"version": map[string]interface{}{
+			"number":                              s.version,
+			"build_flavor":                        "default",
+			"build_type":                          "tar",
+			"build_hash":                          "0000000",
+			"build_date":                          "2018-01-01T00:00:00.000000Z",
+			"build_snapshot":                      false,
+			"lucene_version":                      "7.7.3",
+			"minimum_wire_compatibility_version":  fmt.Sprintf("%d.8.0", s.major-1),
+			"minimum_index_compatibility_version": fmt.Sprintf("%d.0.0", s.major-1),
+		},
+		"tagline": "You Know, for Search",
+	}, nil
+}
+
+func (s *Server) health(r *request) (int, interface{}, error) {
+	return http.StatusOK, map[string]interface{}{
+		"cluster_name":          "estest",
+		"status":                "green",
+		"timed_out":             false,
+		"number_of_nodes":       1,
+		"number_of_data_nodes":  1,
+		"active_primary_shards": len(s.indices),
+		"active_shards":         len(s.indices),
+		"relocating_shards":     0,
+		"initializing_shards":   0,
+		"unassigned_shards":     0,
+	}, nil
+}
+
+func (s *Server) template(r *request, composable bool, name string) (int, interface{}, error) {
+	templates := s.templates
+	if composable {
+		if !s.typeless() || s.major == 7 && s.minor < 8 {
+			return 0, nil, badRequest("no handler found for uri [/_index_template/%s] and method [%s]", name, r.method)
+		}
+		templates = s.composable
+	}
+	switch r.method {
+	case http.MethodPut, http.MethodPost:
+		var template map[string]interface{}
+		if err := r.decode(&template); err != nil {
+			return 0, nil, err
+		}
+		if template == nil {
+			return 0, nil, badRequest("template is missing")
+		}
+		templates[name] = template
+		return http.StatusOK, acknowledged(), nil
+	case http.MethodGet, http.MethodHead:
+		template, ok := templates[name]
+		if !ok {
+			return 0, nil, &esError{status: http.StatusNotFound, errType: "resource_not_found_exception", reason: "template [" + name + "] missing"}
+		}
+		if composable {
+			return http.StatusOK, map[string]interface{}{
+				"index_templates": []interface{}{map[string]interface{}{"name": name, "index_template": template}},
+			}, nil
+		}
+		return http.StatusOK, map[string]interface{}{name: template}, nil
+	case http.MethodDelete:
+		if _, ok := templates[name]; !ok {
+			return 0, nil, &esError{status: http.StatusNotFound, errType: "resource_not_found_exception", reason: "template [" + name + "] missing"}
+		}
+		delete(templates, name)
+		return http.StatusOK, acknowledged(), nil
+	}
+	return 0, nil, badRequest("method [%s] is not allowed", r.method)
+}
+
+func acknowledged() map[string]interface{} {
+	return map[string]interface{}{"acknowledged": true}
+}
+
+func shards(n int) map[string]interface{} {
+	return map[string]interface{}{
+		"_shards": map[string]interface{}{"total": n, "successful": n, "failed": 0},
+	}
+}
+
+// sortedKeys returns the keys of the map in alphabetical order.
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
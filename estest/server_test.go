@@ -0,0 +1,220 @@
+package estest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// do sends the request to the server and returns the status code and the decoded body.
+func do(t *testing.T, s *Server, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("could not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("could not do request: %s", err)
+	}
+	defer res.Body.Close()
+	result := map[string]interface{}{}
+	if method != http.MethodHead {
+		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+			t.Fatalf("could not decode response of %s %s: %s", method, path, err)
+		}
+	}
+	return res.StatusCode, result
+}
+
+// ids returns the ids of the hits in a search response.
+func ids(result map[string]interface{}) []string {
+	var ids []string
+	hits, _ := result["hits"].(map[string]interface{})
+	list, _ := hits["hits"].([]interface{})
+	for _, hit := range list {
+		ids = append(ids, hit.(map[string]interface{})["_id"].(string))
+	}
+	return ids
+}
+
+func indexDocuments(t *testing.T, s *Server) {
+	t.Helper()
+	docs := []string{
+		`{"name":"alpha","count":5,"tags":["a","b"],"date":"2020-01-01T10:00:00Z"}`,
+		`{"name":"beta","count":10,"tags":["b"],"date":"2020-01-03T10:00:00Z"}`,
+		`{"name":"gamma","count":15,"date":"2020-01-03T12:00:00Z"}`,
+	}
+	for i, doc := range docs {
+		if status, result := do(t, s, http.MethodPut, "/test/doc/"+strconv.Itoa(i+1), doc); status != http.StatusCreated {
+			t.Fatalf("could not index document: %d %v", status, result)
+		}
+	}
+}
+
+func TestServer_Search(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	indexDocuments(t, s)
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"match all", `{}`, []string{"1", "2", "3"}},
+		{"term", `{"query":{"term":{"name.keyword":"beta"}}}`, []string{"2"}},
+		{"term on array", `{"query":{"term":{"tags":"b"}}}`, []string{"1", "2"}},
+		{"terms", `{"query":{"terms":{"count":[5,15]}}}`, []string{"1", "3"}},
+		{"range", `{"query":{"range":{"count":{"gt":5,"lte":15}}}}`, []string{"2", "3"}},
+		{"date range", `{"query":{"range":{"date":{"gte":"2020-01-02"}}}}`, []string{"2", "3"}},
+		{"bool", `{"query":{"bool":{"should":[{"term":{"name":"alpha"}},{"term":{"name":"gamma"}}],"must_not":{"term":{"count":15}}}}}`, []string{"1"}},
+		{"exists", `{"query":{"exists":{"field":"tags"}}}`, []string{"1", "2"}},
+		{"sort", `{"sort":[{"count":"desc"}]}`, []string{"3", "2", "1"}},
+		{"paging", `{"sort":["count"],"from":1,"size":1}`, []string{"2"}},
+		{"search after", `{"sort":[{"count":{"order":"asc"}}],"search_after":[5]}`, []string{"2", "3"}},
+	}
+	for _, test := range tests {
+		status, result := do(t, s, http.MethodPost, "/test/_search", test.body)
+		if status != http.StatusOK {
+			t.Errorf("%s: unexpected status %d: %v", test.name, status, result)
+			continue
+		}
+		if hits := ids(result); !reflect.DeepEqual(hits, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, hits)
+		}
+	}
+	if status, _ := do(t, s, http.MethodPost, "/test/_search", `{"query":{"fuzzy":{"name":"alpah"}}}`); status != http.StatusBadRequest {
+		t.Errorf("expected bad request for unsupported query, got %d", status)
+	}
+	if status, _ := do(t, s, http.MethodPost, "/missing/_search", `{}`); status != http.StatusNotFound {
+		t.Errorf("expected not found for missing index, got %d", status)
+	}
+}
+
+func TestServer_TotalHits(t *testing.T) {
+	for version, expected := range map[string]interface{}{
+		"6.8.23": float64(3),
+		"7.17.0": map[string]interface{}{"value": float64(3), "relation": "eq"},
+	} {
+		s := NewServerVersion(version)
+		indexDocuments(t, s)
+		_, result := do(t, s, http.MethodGet, "/test/_search", "")
+		if total := result["hits"].(map[string]interface{})["total"]; !reflect.DeepEqual(total, expected) {
+			t.Errorf("%s: expected total %v, got %v", version, expected, total)
+		}
+		_, result = do(t, s, http.MethodGet, "/test/_search?rest_total_hits_as_int=true", "")
+		if total := result["hits"].(map[string]interface{})["total"]; total != float64(3) {
+			t.Errorf("%s: expected total as int, got %v", version, total)
+		}
+		s.Close()
+	}
+}
+
+func TestServer_Aggregations(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	indexDocuments(t, s)
+	_, result := do(t, s, http.MethodPost, "/test/_search", `{"size":0,"aggs":{
+		"tags":{"terms":{"field":"tags"}},
+		"min":{"min":{"field":"count"}},
+		"max":{"max":{"field":"count"}},
+		"unique":{"cardinality":{"field":"tags"}},
+		"days":{"date_histogram":{"field":"date","interval":"day"}},
+		"names":{"composite":{"size":2,"sources":[{"name":{"terms":{"field":"name"}}}],"after":{"name":"alpha"}}}
+	}}`)
+	b, _ := json.Marshal(result["aggregations"])
+	expected := `{"days":{"buckets":[` +
+		`{"doc_count":1,"key":1577836800000,"key_as_string":"2020-01-01T00:00:00.000Z"},` +
+		`{"doc_count":0,"key":1577923200000,"key_as_string":"2020-01-02T00:00:00.000Z"},` +
+		`{"doc_count":2,"key":1578009600000,"key_as_string":"2020-01-03T00:00:00.000Z"}]},` +
+		`"max":{"value":15},"min":{"value":5},` +
+		`"names":{"after_key":{"name":"gamma"},"buckets":[{"doc_count":1,"key":{"name":"beta"}},{"doc_count":1,"key":{"name":"gamma"}}]},` +
+		`"tags":{"buckets":[{"doc_count":2,"key":"b"},{"doc_count":1,"key":"a"}],"doc_count_error_upper_bound":0,"sum_other_doc_count":0},` +
+		`"unique":{"value":2}}`
+	if string(b) != expected {
+		t.Fatalf("unexpected aggregations:\n%s\nexpected:\n%s", b, expected)
+	}
+}
+
+func TestServer_Update(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	indexDocuments(t, s)
+	if status, result := do(t, s, http.MethodPost, "/test/doc/1/_update", `{"script":{"source":"ctx._source.count += params.n; ctx._source.name = 'changed'","params":{"n":2}}}`); status != http.StatusOK {
+		t.Fatalf("could not update document: %d %v", status, result)
+	}
+	_, result := do(t, s, http.MethodGet, "/test/doc/1", "")
+	source := result["_source"].(map[string]interface{})
+	if source["count"] != float64(7) || source["name"] != "changed" || result["_version"] != float64(2) {
+		t.Fatalf("unexpected document after update: %v", result)
+	}
+	if status, _ := do(t, s, http.MethodPost, "/test/doc/1/_update", `{"script":"ctx._source.count = Math.max(1, 2)"}`); status != http.StatusBadRequest {
+		t.Fatalf("expected bad request for unsupported script, got %d", status)
+	}
+	s.HandleScript("ctx._source.count = Math.max(1, 2)", func(source, params map[string]interface{}) error {
+		source["count"] = 2
+		return nil
+	})
+	if status, result := do(t, s, http.MethodPost, "/test/doc/1/_update", `{"script":"ctx._source.count = Math.max(1, 2)"}`); status != http.StatusOK {
+		t.Fatalf("registered script failed: %d %v", status, result)
+	}
+	_, result = do(t, s, http.MethodPost, "/test/doc/1/_update", `{"doc":{"count":2}}`)
+	if result["result"] != "noop" {
+		t.Fatalf("expected noop for unchanged document, got %v", result)
+	}
+	_, result = do(t, s, http.MethodPost, "/test/_update_by_query", `{"query":{"range":{"count":{"gte":10}}},"script":"ctx._source.big = true"}`)
+	if result["updated"] != float64(2) {
+		t.Fatalf("expected 2 updated documents, got %v", result)
+	}
+	_, result = do(t, s, http.MethodPost, "/test/_delete_by_query", `{"query":{"term":{"big":true}}}`)
+	if result["deleted"] != float64(2) {
+		t.Fatalf("expected 2 deleted documents, got %v", result)
+	}
+}
+
+func TestServer_BulkAndScroll(t *testing.T) {
+	s := NewServerVersion("7.17.0")
+	defer s.Close()
+	body := `{"index":{"_id":"1"}}
+{"field":1}
+{"create":{"_id":"1"}}
+{"field":2}
+{"index":{"_id":"2"}}
+null
+{"index":{"_id":"3"}}
+{"field":3}
+{"delete":{"_id":"3"}}
+`
+	status, result := do(t, s, http.MethodPost, "/bulk/_bulk", body)
+	if status != http.StatusOK || result["errors"] != true {
+		t.Fatalf("unexpected bulk result: %d %v", status, result)
+	}
+	var statuses []float64
+	for _, item := range result["items"].([]interface{}) {
+		for _, value := range item.(map[string]interface{}) {
+			statuses = append(statuses, value.(map[string]interface{})["status"].(float64))
+		}
+	}
+	if expected := []float64{201, 409, 400, 201, 200}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected item statuses %v, got %v", expected, statuses)
+	}
+	for _, id := range []string{"k", "l", "m", "n", "o"} {
+		do(t, s, http.MethodPut, "/bulk/_doc/"+id, `{"field":1}`)
+	}
+	var scrolled []string
+	_, result = do(t, s, http.MethodPost, "/bulk/_search?scroll=1m", `{"size":2,"sort":["_doc"]}`)
+	for len(ids(result)) > 0 {
+		scrolled = append(scrolled, ids(result)...)
+		_, result = do(t, s, http.MethodPost, "/_search/scroll", `{"scroll":"1m","scroll_id":"`+result["_scroll_id"].(string)+`"}`)
+	}
+	if expected := []string{"1", "k", "l", "m", "n", "o"}; !reflect.DeepEqual(scrolled, expected) {
+		t.Fatalf("expected scrolled ids %v, got %v", expected, scrolled)
+	}
+	if status, _ := do(t, s, http.MethodDelete, "/_search/scroll", `{"scroll_id":"estest-scroll-1"}`); status != http.StatusOK {
+		t.Fatalf("could not clear scroll: %d", status)
+	}
+}
@@ -0,0 +1,78 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+)
+
+// DocumentExists returns true, if the document with the id exists in a specific index.
+func (c *Client) DocumentExists(index, doctype, id string) (bool, error) {
+	return c.DocumentExistsContext(context.Background(), index, doctype, id)
+}
+
+// DocumentExistsContext is like DocumentExists, but aborts the request when ctx is done.
+func (c *Client) DocumentExistsContext(ctx context.Context, index, doctype, id string) (bool, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "DocumentExists", index, doctype)
+	defer span.End()
+	exists, err := c.head(ctx, c.documentPath(index, doctype, id))
+	if err != nil {
+		return false, fmt.Errorf("could not check document: %w", err)
+	}
+	return exists, nil
+}
+
+// IndexExists returns true, if the index exists. The index may also be an alias,
+// a comma separated list or a wildcard pattern, then all of them have to exist.
+func (c *Client) IndexExists(index string) (bool, error) {
+	return c.IndexExistsContext(context.Background(), index)
+}
+
+// IndexExistsContext is like IndexExists, but aborts the request when ctx is done.
+func (c *Client) IndexExistsContext(ctx context.Context, index string) (bool, error) {
+	ctx, span := c.startOperation(ctx, APIIndex, "IndexExists", index, "")
+	defer span.End()
+	if index == "" {
+		return false, errors.New("could not check index: index is empty")
+	}
+	exists, err := c.head(ctx, index)
+	if err != nil {
+		return false, fmt.Errorf("could not check index: %w", err)
+	}
+	return exists, nil
+}
+
+// AliasExists returns true, if the alias exists. If index is not empty, the alias
+// has to point to the index.
+func (c *Client) AliasExists(index, alias string) (bool, error) {
+	return c.AliasExistsContext(context.Background(), index, alias)
+}
+
+// AliasExistsContext is like AliasExists, but aborts the request when ctx is done.
+func (c *Client) AliasExistsContext(ctx context.Context, index, alias string) (bool, error) {
+	ctx, span := c.startOperation(ctx, APIIndex, "AliasExists", index, "")
+	defer span.End()
+	exists, err := c.head(ctx, path.Join(index, "_alias", alias))
+	if err != nil {
+		return false, fmt.Errorf("could not check alias: %w", err)
+	}
+	return exists, nil
+}
+
+// TemplateExists returns true, if the template with the id exists. Like AddTemplate,
+// it checks index templates since Elasticsearch 7.8 and legacy templates before.
+func (c *Client) TemplateExists(id string) (bool, error) {
+	return c.TemplateExistsContext(context.Background(), id)
+}
+
+// TemplateExistsContext is like TemplateExists, but aborts the request when ctx is done.
+func (c *Client) TemplateExistsContext(ctx context.Context, id string) (bool, error) {
+	ctx, span := c.startOperation(ctx, APITemplate, "TemplateExists", "", "")
+	defer span.End()
+	exists, err := c.head(ctx, c.templatePath(id))
+	if err != nil {
+		return false, fmt.Errorf("could not check template: %w", err)
+	}
+	return exists, nil
+}
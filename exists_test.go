@@ -0,0 +1,80 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NextronSystems/go-elasticsearch/estest"
+)
+
+func TestClient_Exists(t *testing.T) {
+	forEachVersion(t, fakeVersions, func(t *testing.T, server *estest.Server, client *Client) {
+		if err := client.InsertDocument("exists", "doc", "1", map[string]interface{}{"field": "value"}, RefreshTrue); err != nil {
+			t.Fatalf("could not insert document: %s", err)
+		}
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/exists/_alias/current", nil)
+		if err != nil {
+			t.Fatalf("could not create request: %s", err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("could not add alias: %s", err)
+		}
+		res.Body.Close()
+		if err := client.AddTemplate("template", map[string]interface{}{"index_patterns": []string{"exists*"}}); err != nil {
+			t.Fatalf("could not add template: %s", err)
+		}
+
+		checks := []struct {
+			name     string
+			check    func() (bool, error)
+			expected bool
+		}{
+			{"document", func() (bool, error) { return client.DocumentExists("exists", "doc", "1") }, true},
+			{"missing document", func() (bool, error) { return client.DocumentExists("exists", "doc", "2") }, false},
+			{"document in missing index", func() (bool, error) { return client.DocumentExists("missing", "doc", "1") }, false},
+			{"index", func() (bool, error) { return client.IndexExists("exists") }, true},
+			{"index by alias", func() (bool, error) { return client.IndexExists("current") }, true},
+			{"missing index", func() (bool, error) { return client.IndexExists("missing") }, false},
+			{"alias", func() (bool, error) { return client.AliasExists("", "current") }, true},
+			{"alias of index", func() (bool, error) { return client.AliasExists("exists", "current") }, true},
+			{"missing alias", func() (bool, error) { return client.AliasExists("exists", "missing") }, false},
+			{"template", func() (bool, error) { return client.TemplateExists("template") }, true},
+			{"missing template", func() (bool, error) { return client.TemplateExists("missing") }, false},
+		}
+		for _, check := range checks {
+			exists, err := check.check()
+			if err != nil {
+				t.Fatalf("%s: could not check: %s", check.name, err)
+			}
+			if exists != check.expected {
+				t.Errorf("%s: expected %t, got %t", check.name, check.expected, exists)
+			}
+		}
+	})
+}
+
+func TestClient_ExistsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(infoResponse))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	_, err = client.IndexExists("index")
+	var esErr *ElasticsearchError
+	if !errors.As(err, &esErr) || esErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+	if _, err := client.IndexExists(""); err == nil {
+		t.Fatal("expected error for empty index")
+	}
+}
@@ -1,6 +1,9 @@
 package elasticsearch
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+)
 
 // Status constants for Elasticsearch health
 const (
@@ -11,7 +14,14 @@ const (
 
 // Health returns the health status of Elasticsearch (green, yellow, red).
 func (c *Client) Health() (string, error) {
-	res, err := c.get("_cluster/health", nil)
+	return c.HealthContext(context.Background())
+}
+
+// HealthContext is like Health, but aborts the request when ctx is done.
+func (c *Client) HealthContext(ctx context.Context) (string, error) {
+	ctx, span := c.startOperation(ctx, APICluster, "Health", "", "")
+	defer span.End()
+	res, err := c.get(ctx, "_cluster/health", nil)
 	if err != nil {
 		return StatusRed, err
 	}
@@ -6,7 +6,7 @@ var healthClient *Client
 
 func init() {
 	var err error
-	if healthClient, err = Open("http://localhost:9200"); err != nil {
+	if healthClient, err = Open(testServer.URL); err != nil {
 		panic(err)
 	}
 	if err := healthClient.Ping(); err != nil {
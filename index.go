@@ -1,21 +1,38 @@
 package elasticsearch
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // DeleteIndex deletes a whole index.
 func (c *Client) DeleteIndex(index string) error {
-	_, err := c.delete_(index, nil)
+	return c.DeleteIndexContext(context.Background(), index)
+}
+
+// DeleteIndexContext is like DeleteIndex, but aborts the request when ctx is done.
+func (c *Client) DeleteIndexContext(ctx context.Context, index string) error {
+	ctx, span := c.startOperation(ctx, APIIndex, "DeleteIndex", index, "")
+	defer span.End()
+	_, err := c.delete_(ctx, index, nil, true)
 	if err != nil {
-		return fmt.Errorf("could not delete index: %s", err)
+		return fmt.Errorf("could not delete index: %w", err)
 	}
 	return nil
 }
 
 // Refresh refreshs a index. Useful if multiple updates or inserts were done without refresh = true.
 func (c *Client) Refresh(index string) error {
-	_, err := c.post(index+"/_refresh", nil)
+	return c.RefreshContext(context.Background(), index)
+}
+
+// RefreshContext is like Refresh, but aborts the request when ctx is done.
+func (c *Client) RefreshContext(ctx context.Context, index string) error {
+	ctx, span := c.startOperation(ctx, APIIndex, "Refresh", index, "")
+	defer span.End()
+	_, err := c.post(ctx, index+"/_refresh", nil)
 	if err != nil {
-		return fmt.Errorf("could not refresh index: %s", err)
+		return fmt.Errorf("could not refresh index: %w", err)
 	}
 	return nil
 }
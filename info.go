@@ -0,0 +1,105 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedProduct is returned by ClusterInfo.CheckProduct, if the server is not
+// a supported Elasticsearch.
+var ErrUnsupportedProduct = errors.New("server is not a supported elasticsearch")
+
+// elasticsearchTagline is returned by all versions of Elasticsearch on the root endpoint.
+const elasticsearchTagline = "You Know, for Search"
+
+// ClusterInfo contains the basic information about the cluster and the node
+// that answered the request.
+type ClusterInfo struct {
+	NodeName    string         `json:"name"`
+	ClusterName string         `json:"cluster_name"`
+	ClusterUUID string         `json:"cluster_uuid"`
+	Version     ClusterVersion `json:"version"`
+	Tagline     string         `json:"tagline"`
+	// Product is the X-Elastic-Product header, that is sent since Elasticsearch 7.14.
+	Product string `json:"-"`
+}
+
+// ClusterVersion contains the version and the build of the node.
+type ClusterVersion struct {
+	Number string `json:"number"`
+	// Distribution is only returned by OpenSearch.
+	Distribution                     string `json:"distribution"`
+	BuildFlavor                      string `json:"build_flavor"`
+	BuildType                        string `json:"build_type"`
+	BuildHash                        string `json:"build_hash"`
+	BuildDate                        string `json:"build_date"`
+	BuildSnapshot                    bool   `json:"build_snapshot"`
+	LuceneVersion                    string `json:"lucene_version"`
+	MinimumWireCompatibilityVersion  string `json:"minimum_wire_compatibility_version"`
+	MinimumIndexCompatibilityVersion string `json:"minimum_index_compatibility_version"`
+}
+
+// Info returns the information about the cluster. Like Ping, it detects the version of
+// Elasticsearch.
+func (c *Client) Info() (*ClusterInfo, error) {
+	return c.InfoContext(context.Background())
+}
+
+// InfoContext is like Info, but aborts the request when ctx is done.
+func (c *Client) InfoContext(ctx context.Context) (*ClusterInfo, error) {
+	ctx, span := c.startOperation(ctx, APICluster, "Info", "", "")
+	defer span.End()
+	res, err := c.perform(ctx, "GET", "", nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not get cluster info: %w", err)
+	}
+	info := &ClusterInfo{}
+	if err := json.Unmarshal(res.body, info); err != nil {
+		return nil, fmt.Errorf("could not unmarshal cluster info: %w", err)
+	}
+	info.Product = res.header.Get("X-Elastic-Product")
+	if err := c.detectVersion(*info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// detectVersion sets the version of the client to the version of the cluster.
+func (c *Client) detectVersion(info ClusterInfo) error {
+	version, err := parseVersion(info.Version.Number)
+	if err != nil {
+		return fmt.Errorf("could not detect elasticsearch version: %w", err)
+	}
+	c.setVersion(version)
+	return nil
+}
+
+// CheckProduct returns an error wrapping ErrUnsupportedProduct, if the server is not
+// Elasticsearch 6 or newer. Since Elasticsearch 7.14, the X-Elastic-Product header is
+// required, older versions are identified by the tagline and the build flavor.
+func (i *ClusterInfo) CheckProduct() error {
+	if i.Version.Distribution == "opensearch" {
+		return fmt.Errorf("%w: server is opensearch %s", ErrUnsupportedProduct, i.Version.Number)
+	}
+	version, err := parseVersion(i.Version.Number)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedProduct, err)
+	}
+	switch {
+	case i.Product == "Elasticsearch":
+		return nil
+	case i.Product != "":
+		return fmt.Errorf("%w: server is %s", ErrUnsupportedProduct, i.Product)
+	case version.Major < 6:
+		return fmt.Errorf("%w: version %s is not supported", ErrUnsupportedProduct, version)
+	case version.AtLeast(7, 14):
+		return fmt.Errorf("%w: X-Elastic-Product header is missing", ErrUnsupportedProduct)
+	case i.Tagline != elasticsearchTagline:
+		return fmt.Errorf("%w: unexpected tagline %q", ErrUnsupportedProduct, i.Tagline)
+	case version.Major == 7 && i.Version.BuildFlavor != "default":
+		return fmt.Errorf("%w: build flavor %q is not supported", ErrUnsupportedProduct, i.Version.BuildFlavor)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const infoResponse = `{
+  "name" : "node-1",
+  "cluster_name" : "elasticsearch",
+  "cluster_uuid" : "wXwIXmJ5SRW6hNuqUUTUcA",
+  "version" : {
+    "number" : "8.11.1",
+    "build_flavor" : "default",
+    "build_type" : "docker",
+    "build_hash" : "6f9ff581fbcde658e6f69d6ce03050f060d1fd0c",
+    "build_date" : "2023-11-11T10:05:59.421038163Z",
+    "build_snapshot" : false,
+    "lucene_version" : "9.8.0",
+    "minimum_wire_compatibility_version" : "7.17.0",
+    "minimum_index_compatibility_version" : "7.0.0"
+  },
+  "tagline" : "You Know, for Search"
+}`
+
+// infoServer answers the root endpoint with the body and the X-Elastic-Product header.
+func infoServer(product, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if product != "" {
+			w.Header().Set("X-Elastic-Product", product)
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestClient_Info(t *testing.T) {
+	server := infoServer("Elasticsearch", infoResponse)
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	info, err := client.Info()
+	if err != nil {
+		t.Fatalf("could not get info: %s", err)
+	}
+	expected := ClusterInfo{
+		NodeName:    "node-1",
+		ClusterName: "elasticsearch",
+		ClusterUUID: "wXwIXmJ5SRW6hNuqUUTUcA",
+		Version: ClusterVersion{
+			Number:                           "8.11.1",
+			BuildFlavor:                      "default",
+			BuildType:                        "docker",
+			BuildHash:                        "6f9ff581fbcde658e6f69d6ce03050f060d1fd0c",
+			BuildDate:                        "2023-11-11T10:05:59.421038163Z",
+			LuceneVersion:                    "9.8.0",
+			MinimumWireCompatibilityVersion:  "7.17.0",
+			MinimumIndexCompatibilityVersion: "7.0.0",
+		},
+		Tagline: "You Know, for Search",
+		Product: "Elasticsearch",
+	}
+	if *info != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *info)
+	}
+	if client.Version() != (Version{8, 11, 1}) {
+		t.Fatalf("version was not detected: %s", client.Version())
+	}
+}
+
+func TestClusterInfo_CheckProduct(t *testing.T) {
+	tests := []struct {
+		name  string
+		info  ClusterInfo
+		valid bool
+	}{
+		{"elasticsearch 8", ClusterInfo{Product: "Elasticsearch", Version: ClusterVersion{Number: "8.11.1"}}, true},
+		{"elasticsearch 7.14 without header", ClusterInfo{Tagline: elasticsearchTagline, Version: ClusterVersion{Number: "7.14.0", BuildFlavor: "default"}}, false},
+		{"elasticsearch 7.10", ClusterInfo{Tagline: elasticsearchTagline, Version: ClusterVersion{Number: "7.10.2", BuildFlavor: "default"}}, true},
+		{"elasticsearch 7.10 oss", ClusterInfo{Tagline: elasticsearchTagline, Version: ClusterVersion{Number: "7.10.2", BuildFlavor: "oss"}}, false},
+		{"elasticsearch 6", ClusterInfo{Tagline: elasticsearchTagline, Version: ClusterVersion{Number: "6.1.1"}}, true},
+		{"elasticsearch 5", ClusterInfo{Tagline: elasticsearchTagline, Version: ClusterVersion{Number: "5.6.16"}}, false},
+		{"unknown tagline", ClusterInfo{Tagline: "Hello", Version: ClusterVersion{Number: "6.1.1"}}, false},
+		{"other product", ClusterInfo{Product: "Kibana", Version: ClusterVersion{Number: "8.11.1"}}, false},
+		{"opensearch", ClusterInfo{Tagline: "The OpenSearch Project: https://opensearch.org/", Version: ClusterVersion{Number: "2.11.0", Distribution: "opensearch"}}, false},
+		{"missing version", ClusterInfo{Tagline: elasticsearchTagline}, false},
+	}
+	for _, test := range tests {
+		err := test.info.CheckProduct()
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrUnsupportedProduct) {
+			t.Errorf("%s: expected unsupported product, got: %v", test.name, err)
+		}
+	}
+}
+
+func TestNewClient_CheckProduct(t *testing.T) {
+	server := infoServer("", `{"name":"node-1","version":{"distribution":"opensearch","number":"2.11.0"},"tagline":"The OpenSearch Project: https://opensearch.org/"}`)
+	defer server.Close()
+	if _, err := NewClient(Config{URLs: []string{server.URL}, CheckProduct: true}); !errors.Is(err, ErrUnsupportedProduct) {
+		t.Fatalf("expected unsupported product, got: %v", err)
+	}
+	if _, err := NewClient(Config{URLs: []string{server.URL}}); err != nil {
+		t.Fatalf("product should only be checked if enabled: %s", err)
+	}
+
+	server = infoServer("Elasticsearch", infoResponse)
+	defer server.Close()
+	client, err := NewClient(Config{URLs: []string{server.URL}, CheckProduct: true})
+	if err != nil {
+		t.Fatalf("could not create client: %s", err)
+	}
+	if !client.typeless() {
+		t.Fatalf("version was not detected: %s", client.Version())
+	}
+}
@@ -0,0 +1,37 @@
+package elasticsearch
+
+import "net/http"
+
+// RoundTripFunc sends a request to Elasticsearch and returns the response.
+type RoundTripFunc func(r *http.Request) (*http.Response, error)
+
+// Interceptor is called for every request sent to Elasticsearch, including retries.
+// It may modify the request, e.g. add headers, and has to call next to send it.
+// The response returned by next can be inspected or replaced. An interceptor can
+// also answer the request itself without calling next.
+//
+// Errors returned by an interceptor are not retried, unless they wrap an error
+// returned by next.
+type Interceptor func(r *http.Request, next RoundTripFunc) (*http.Response, error)
+
+// chain returns a RoundTripFunc passing the request through all interceptors in
+// the given order before it is sent by the http client.
+func (c *Client) chain(interceptors []Interceptor) RoundTripFunc {
+	next := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if log.DebugMode() {
+			log.Debugf("Elasticsearch Request: %s", string(dumpRequest(r)))
+		}
+		resp, err := c.httpClient.Do(r)
+		if err != nil {
+			return nil, &connectionError{err: err}
+		}
+		return resp, nil
+	})
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func(r *http.Request) (*http.Response, error) {
+			return interceptor(r, inner)
+		}
+	}
+	return next
+}
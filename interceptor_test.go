@@ -0,0 +1,109 @@
+package elasticsearch
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_InterceptorHeaders(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	var order []string
+	client, err := NewClient(Config{
+		URLs: []string{server.URL},
+		Interceptors: []Interceptor{
+			func(r *http.Request, next RoundTripFunc) (*http.Response, error) {
+				order = append(order, "first")
+				r.Header.Set("X-Opaque-Id", "request-1")
+				return next(r)
+			},
+			func(r *http.Request, next RoundTripFunc) (*http.Response, error) {
+				order = append(order, "second")
+				r.Header.Set("X-Tenant", "tenant-1")
+				return next(r)
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	if headers.Get("X-Opaque-Id") != "request-1" || headers.Get("X-Tenant") != "tenant-1" {
+		t.Fatalf("headers not set: %v", headers)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Fatalf("unexpected order of interceptors: %v", order)
+	}
+}
+
+func TestClient_InterceptorAudit(t *testing.T) {
+	server, _ := statusServer(nil, http.StatusTooManyRequests)
+	defer server.Close()
+	var audit []string
+	client, err := NewClient(Config{
+		URLs:        []string{server.URL},
+		RetryPolicy: fastRetryPolicy(2),
+		Interceptors: []Interceptor{
+			func(r *http.Request, next RoundTripFunc) (*http.Response, error) {
+				resp, err := next(r)
+				if err != nil {
+					return nil, err
+				}
+				audit = append(audit, r.Method+" "+r.URL.Path+" "+resp.Status)
+				return resp, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Refresh("testclient_interceptoraudit"); err != nil {
+		t.Fatalf("could not refresh: %s", err)
+	}
+	if strings.Join(audit, "\n") != "POST /testclient_interceptoraudit/_refresh 429 Too Many Requests\nPOST /testclient_interceptoraudit/_refresh 200 OK" {
+		t.Fatalf("unexpected audit trail: %v", audit)
+	}
+}
+
+func TestClient_InterceptorShortCircuit(t *testing.T) {
+	client, err := NewClient(Config{
+		URLs: []string{"http://elasticsearch:9200"},
+		Interceptors: []Interceptor{
+			func(r *http.Request, next RoundTripFunc) (*http.Response, error) {
+				if r.URL.Path == "/_cluster/health" {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(strings.NewReader(`{"status":"yellow"}`)),
+					}, nil
+				}
+				return nil, errors.New("unexpected request")
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	health, err := client.Health()
+	if err != nil {
+		t.Fatalf("could not get health: %s", err)
+	}
+	if health != StatusYellow {
+		t.Fatalf("expected health yellow, got: %s", health)
+	}
+	if err := client.Ping(); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Fatalf("expected interceptor error, got: %v", err)
+	}
+	if client.pool.nodes[0].failures != 0 {
+		t.Fatal("interceptor error must not mark the node as dead")
+	}
+}
@@ -0,0 +1,45 @@
+package elasticsearch
+
+import "time"
+
+// API groups the requests of the client for metrics.
+type API string
+
+// APIs of the client
+const (
+	APIDocument    API = "document"
+	APISearch      API = "search"
+	APIScroll      API = "scroll"
+	APIBulk        API = "bulk"
+	APIAggregation API = "aggregation"
+	APIIndex       API = "index"
+	APITemplate    API = "template"
+	APISnapshot    API = "snapshot"
+	APICluster     API = "cluster"
+)
+
+// Metrics receives measurements of all requests done by a Client. Implement it
+// to export the measurements, e.g. as Prometheus metrics or with expvar.
+// The methods are called concurrently.
+type Metrics interface {
+	// ObserveRequest is called after every attempt of a request with its duration.
+	// The status code is 0, if the node could not be reached.
+	ObserveRequest(api API, method string, statusCode int, duration time.Duration)
+	// IncRetries is called before a failed request is sent again.
+	IncRetries(api API)
+	// IncThrottled is called for every response with 429 Too Many Requests.
+	IncThrottled(api API)
+	// AddBulkItemFailures is called with the number of documents that could not be imported.
+	AddBulkItemFailures(api API, failures int)
+}
+
+// discardMetrics will be used as default metrics and will discard all measurements.
+type discardMetrics struct{}
+
+func (discardMetrics) ObserveRequest(_ API, _ string, _ int, _ time.Duration) {}
+
+func (discardMetrics) IncRetries(_ API) {}
+
+func (discardMetrics) IncThrottled(_ API) {}
+
+func (discardMetrics) AddBulkItemFailures(_ API, _ int) {}
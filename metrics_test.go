@@ -0,0 +1,113 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics records all measurements as strings.
+type recordingMetrics struct {
+	mu           sync.Mutex
+	measurements []string
+}
+
+func (r *recordingMetrics) record(format string, a ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.measurements = append(r.measurements, fmt.Sprintf(format, a...))
+}
+
+func (r *recordingMetrics) ObserveRequest(api API, method string, statusCode int, duration time.Duration) {
+	if duration <= 0 {
+		r.record("invalid duration %s", duration)
+	}
+	r.record("request %s %s %d", api, method, statusCode)
+}
+
+func (r *recordingMetrics) IncRetries(api API) {
+	r.record("retry %s", api)
+}
+
+func (r *recordingMetrics) IncThrottled(api API) {
+	r.record("throttled %s", api)
+}
+
+func (r *recordingMetrics) AddBulkItemFailures(api API, failures int) {
+	r.record("bulk failures %s %d", api, failures)
+}
+
+func TestClient_Metrics(t *testing.T) {
+	var throttled bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_bulk"):
+			w.Write([]byte(`{"items":[{"index":{"_id":"1","status":400,"error":{"type":"mapper_parsing_exception"}}},{"index":{"_id":"2","status":201}}]}`))
+		case strings.HasSuffix(r.URL.Path, "/_search") && !throttled:
+			throttled = true
+			w.WriteHeader(http.StatusTooManyRequests)
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			w.Write([]byte(`{"aggregations":{"count_field1":{"value":3}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	metrics := &recordingMetrics{}
+	client, err := NewClient(Config{
+		URLs:        []string{server.URL},
+		Metrics:     metrics,
+		RetryPolicy: fastRetryPolicy(2),
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if _, err := client.InsertDocuments("testclient_metrics", "doc", map[string]map[string]interface{}{"1": {}, "2": {}}); err != nil {
+		t.Fatalf("could not insert documents: %s", err)
+	}
+	if _, err := client.CardinalityAggregate("testclient_metrics", "doc", nil, "field1"); err != nil {
+		t.Fatalf("could not aggregate: %s", err)
+	}
+	if _, err := client.GetDocument("testclient_metrics", "doc", "1"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+	expected := []string{
+		"request bulk PUT 200",
+		"bulk failures bulk 1",
+		"request aggregation GET 429",
+		"throttled aggregation",
+		"retry aggregation",
+		"request aggregation GET 200",
+		"request document GET 404",
+	}
+	if strings.Join(metrics.measurements, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected measurements:\n%s", strings.Join(metrics.measurements, "\n"))
+	}
+}
+
+func TestClient_MetricsConnectionError(t *testing.T) {
+	var counter int32
+	server := countingServer(&counter)
+	server.Close()
+	metrics := &recordingMetrics{}
+	client, err := NewClient(Config{
+		URLs:        []string{server.URL},
+		Metrics:     metrics,
+		RetryPolicy: fastRetryPolicy(2),
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected connection error")
+	}
+	sort.Strings(metrics.measurements)
+	if strings.Join(metrics.measurements, ",") != "request cluster GET 0,request cluster GET 0,retry cluster" {
+		t.Fatalf("unexpected measurements: %v", metrics.measurements)
+	}
+}
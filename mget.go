@@ -0,0 +1,114 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// MultiGetItem identifies a document for MultiGet.
+type MultiGetItem struct {
+	// Index is the index of the document. It is optional, if the index is passed to MultiGet.
+	Index string
+	ID    string
+	// Routing is the routing value, if the document was indexed with routing.
+	Routing string
+	// SourceIncludes and SourceExcludes filter the fields of the returned source.
+	// Wildcards are supported.
+	SourceIncludes []string
+	SourceExcludes []string
+	// NoSource does not return the source of the document.
+	NoSource bool
+}
+
+// MultiGetResult is a document returned by MultiGet.
+type MultiGetResult struct {
+	ID          string                 `json:"_id"`
+	Index       string                 `json:"_index"`
+	Found       bool                   `json:"found"`
+	Version     int64                  `json:"_version"`
+	SeqNo       int64                  `json:"_seq_no"`
+	PrimaryTerm int64                  `json:"_primary_term"`
+	Source      map[string]interface{} `json:"_source"`
+	// Error is set, if the document could not be fetched, e.g. because the index does not exist.
+	Error *ErrorCause `json:"error"`
+}
+
+// GetDocumentsByID returns the documents with the ids in a specific index with a
+// single request. The results are in the order of the ids, missing documents are
+// returned with Found set to false.
+func (c *Client) GetDocumentsByID(index, doctype string, ids []string) ([]MultiGetResult, error) {
+	return c.GetDocumentsByIDContext(context.Background(), index, doctype, ids)
+}
+
+// GetDocumentsByIDContext is like GetDocumentsByID, but aborts the request when ctx is done.
+func (c *Client) GetDocumentsByIDContext(ctx context.Context, index, doctype string, ids []string) ([]MultiGetResult, error) {
+	items := make([]MultiGetItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, MultiGetItem{ID: id})
+	}
+	return c.MultiGetContext(ctx, index, doctype, items)
+}
+
+// MultiGet returns the documents with a single request. The documents may be in
+// different indices, the index is only used for items without index and is optional
+// otherwise. The results are in the order of the items, missing documents are
+// returned with Found set to false.
+func (c *Client) MultiGet(index, doctype string, items []MultiGetItem) ([]MultiGetResult, error) {
+	return c.MultiGetContext(context.Background(), index, doctype, items)
+}
+
+// MultiGetContext is like MultiGet, but aborts the request when ctx is done.
+func (c *Client) MultiGetContext(ctx context.Context, index, doctype string, items []MultiGetItem) ([]MultiGetResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "MultiGet", index, doctype)
+	defer span.End()
+	if len(items) == 0 {
+		return nil, nil
+	}
+	docs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		doc := map[string]interface{}{"_id": item.ID}
+		if item.Index != "" {
+			doc["_index"] = item.Index
+		}
+		if item.Routing != "" {
+			doc["routing"] = item.Routing
+		}
+		switch {
+		case item.NoSource:
+			doc["_source"] = false
+		case item.SourceIncludes != nil || item.SourceExcludes != nil:
+			source := map[string]interface{}{}
+			if item.SourceIncludes != nil {
+				source["includes"] = item.SourceIncludes
+			}
+			if item.SourceExcludes != nil {
+				source["excludes"] = item.SourceExcludes
+			}
+			doc["_source"] = source
+		}
+		docs = append(docs, doc)
+	}
+	b, err := json.Marshal(map[string]interface{}{"docs": docs})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the documents: %w", err)
+	}
+	apipath := "_mget"
+	if index != "" {
+		apipath = c.indexPath(index, doctype) + "/_mget"
+	}
+	b, err = c.get(ctx, apipath, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not get documents: %w", err)
+	}
+	result := struct {
+		Docs []MultiGetResult `json:"docs"`
+	}{}
+	if err := decodeResponse(b, &result); err != nil {
+		return nil, fmt.Errorf("could not decode documents: %w", err)
+	}
+	if len(result.Docs) != len(items) {
+		return nil, fmt.Errorf("could not get documents: expected %d documents, got %d", len(items), len(result.Docs))
+	}
+	return result.Docs, nil
+}
@@ -0,0 +1,54 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NextronSystems/go-elasticsearch/estest"
+)
+
+func TestClient_GetDocumentsByID(t *testing.T) {
+	forEachVersion(t, fakeVersions, func(t *testing.T, server *estest.Server, client *Client) {
+		for _, doc := range []struct{ index, id string }{{"mget1", "a"}, {"mget1", "b"}, {"mget2", "c"}} {
+			document := map[string]interface{}{"id": doc.id, "nested": map[string]interface{}{"x": 1, "y": 2}}
+			if err := client.InsertDocument(doc.index, "doc", doc.id, document, RefreshTrue); err != nil {
+				t.Fatalf("could not insert document: %s", err)
+			}
+		}
+		docs, err := client.GetDocumentsByID("mget1", "doc", []string{"b", "missing", "a"})
+		if err != nil {
+			t.Fatalf("could not get documents: %s", err)
+		}
+		var ids []string
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		if len(docs) != 3 || !docs[0].Found || docs[1].Found || !docs[2].Found || ids[0] != "b" || ids[2] != "a" {
+			t.Fatalf("unexpected documents: %+v", docs)
+		}
+		if docs[0].Source["id"] != "b" || docs[0].Version != 1 || docs[0].Index != "mget1" {
+			t.Fatalf("unexpected document: %+v", docs[0])
+		}
+
+		docs, err = client.MultiGet("mget1", "doc", []MultiGetItem{
+			{ID: "a", SourceIncludes: []string{"nested.*"}, SourceExcludes: []string{"nested.y"}},
+			{Index: "mget2", ID: "c", Routing: "r1", NoSource: true},
+			{Index: "missing", ID: "d"},
+		})
+		if err != nil {
+			t.Fatalf("could not get documents: %s", err)
+		}
+		if source, _ := json.Marshal(docs[0].Source); string(source) != `{"nested":{"x":1}}` {
+			t.Fatalf("unexpected filtered source %s", source)
+		}
+		if !docs[1].Found || docs[1].Index != "mget2" || docs[1].Source != nil {
+			t.Fatalf("unexpected document without source: %+v", docs[1])
+		}
+		if docs[2].Found || docs[2].Error == nil || docs[2].Error.Type != "index_not_found_exception" {
+			t.Fatalf("expected index not found error: %+v", docs[2])
+		}
+		if docs, err := client.MultiGet("", "", []MultiGetItem{{Index: "mget2", ID: "c"}}); err != nil || !docs[0].Found {
+			t.Fatalf("could not get document without default index: %v %+v", err, docs)
+		}
+	})
+}
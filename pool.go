@@ -0,0 +1,125 @@
+package elasticsearch
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultResurrectTimeout is the time a node is considered dead after its first failure.
+const defaultResurrectTimeout = time.Second * 60
+
+// maxResurrectFactor limits how often the resurrect timeout of a repeatedly failing
+// node is doubled.
+const maxResurrectFactor = 32
+
+// node is a single Elasticsearch node of the nodePool.
+type node struct {
+	url       *url.URL
+	failures  int
+	deadUntil time.Time
+}
+
+// dead returns true, if the node failed and its resurrect timeout has not expired yet.
+func (n *node) dead(now time.Time) bool {
+	return n.failures > 0 && now.Before(n.deadUntil)
+}
+
+// nodePool selects the node for the next request in round robin order and keeps
+// track of nodes that could not be reached.
+type nodePool struct {
+	mu               sync.Mutex
+	nodes            []*node
+	current          int
+	resurrectTimeout time.Duration
+}
+
+func newNodePool(urls []*url.URL, resurrectTimeout time.Duration) *nodePool {
+	if resurrectTimeout <= 0 {
+		resurrectTimeout = defaultResurrectTimeout
+	}
+	p := &nodePool{resurrectTimeout: resurrectTimeout}
+	for _, u := range urls {
+		p.nodes = append(p.nodes, &node{url: u})
+	}
+	return p
+}
+
+// len returns the number of nodes in the pool, dead or alive.
+func (p *nodePool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.nodes)
+}
+
+// next returns the next alive node. Dead nodes are resurrected as soon as their
+// timeout expired. If all nodes are dead, the node with the earliest resurrect
+// time is returned, so that a request is never refused without trying.
+func (p *nodePool) next() *node {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var fallback *node
+	for i := 0; i < len(p.nodes); i++ {
+		n := p.nodes[(p.current+i)%len(p.nodes)]
+		if !n.dead(now) {
+			p.current = (p.current + i + 1) % len(p.nodes)
+			return n
+		}
+		if fallback == nil || n.deadUntil.Before(fallback.deadUntil) {
+			fallback = n
+		}
+	}
+	return fallback
+}
+
+// update replaces the nodes of the pool with the given urls. Nodes that are
+// already part of the pool keep their state, nodes missing in urls are dropped.
+func (p *nodePool) update(urls []*url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	known := map[string]*node{}
+	for _, n := range p.nodes {
+		known[n.url.String()] = n
+	}
+	var nodes []*node
+	for _, u := range urls {
+		if n, ok := known[u.String()]; ok {
+			nodes = append(nodes, n)
+			delete(known, u.String())
+			continue
+		}
+		log.Infof("Elasticsearch node %s added", u)
+		nodes = append(nodes, &node{url: u})
+	}
+	for u := range known {
+		log.Infof("Elasticsearch node %s removed", u)
+	}
+	p.nodes = nodes
+	p.current = p.current % len(nodes)
+}
+
+// markDead marks the node as unreachable. The time until the node is tried again
+// doubles with every consecutive failure.
+func (p *nodePool) markDead(n *node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	factor := maxResurrectFactor
+	if n.failures < 5 {
+		factor = 1 << uint(n.failures)
+	}
+	n.failures++
+	n.deadUntil = time.Now().Add(p.resurrectTimeout * time.Duration(factor))
+	log.Infof("Elasticsearch node %s marked as dead for %s", n.url, p.resurrectTimeout*time.Duration(factor))
+}
+
+// markAlive resets the failures of the node after a successful request.
+func (p *nodePool) markAlive(n *node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n.failures > 0 {
+		log.Infof("Elasticsearch node %s is alive again", n.url)
+	}
+	n.failures = 0
+	n.deadUntil = time.Time{}
+}
@@ -0,0 +1,118 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer returns a test server counting the number of received requests.
+func countingServer(counter *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(counter, 1)
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestClient_RoundRobin(t *testing.T) {
+	var counters [3]int32
+	var urls []string
+	for i := range counters {
+		server := countingServer(&counters[i])
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	client, err := Open(urls...)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	for i := 0; i < 9; i++ {
+		if err := client.Ping(); err != nil {
+			t.Fatalf("could not ping: %s", err)
+		}
+	}
+	for i := range counters {
+		if counters[i] != 3 {
+			t.Fatalf("expected 3 requests on node %d, got: %d", i, counters[i])
+		}
+	}
+}
+
+func TestClient_Failover(t *testing.T) {
+	var alive, dead int32
+	aliveServer := countingServer(&alive)
+	defer aliveServer.Close()
+	deadServer := countingServer(&dead)
+	deadServer.Close()
+	client, err := Open(deadServer.URL, aliveServer.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := client.Refresh("testclient_failover"); err != nil {
+			t.Fatalf("request was not retried on alive node: %s", err)
+		}
+	}
+	if alive != 4 {
+		t.Fatalf("expected 4 requests on alive node, got: %d", alive)
+	}
+	if !client.pool.nodes[0].dead(time.Now()) {
+		t.Fatal("expected node to be marked as dead")
+	}
+}
+
+func TestClient_AllNodesDead(t *testing.T) {
+	var counter int32
+	server := countingServer(&counter)
+	server.Close()
+	client, err := NewClient(Config{
+		URLs:        []string{server.URL},
+		RetryPolicy: &RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected connection error")
+	}
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected connection error")
+	} else {
+		t.Logf("error as expected: %s", err)
+	}
+	if client.pool.nodes[0].failures != 2 {
+		t.Fatalf("expected the dead node to be tried again, got %d failures", client.pool.nodes[0].failures)
+	}
+}
+
+func TestNodePool_Resurrect(t *testing.T) {
+	var counter int32
+	server := countingServer(&counter)
+	defer server.Close()
+	client, err := NewClient(Config{
+		URLs:             []string{server.URL, server.URL},
+		ResurrectTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	dead := client.pool.nodes[0]
+	client.pool.markDead(dead)
+	for i := 0; i < 4; i++ {
+		if n := client.pool.next(); n == dead {
+			t.Fatal("dead node was selected before its resurrect timeout")
+		}
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	if dead.failures != 0 {
+		t.Fatal("expected node to be resurrected")
+	}
+}
@@ -0,0 +1,150 @@
+package query
+
+// BoolQuery combines queries with boolean clauses, see Bool.
+type BoolQuery map[string]interface{}
+
+// Bool returns a query combining other queries. The clauses are added with
+// Must, Should, Filter and MustNot. Without clauses, all documents match.
+func Bool() BoolQuery {
+	return BoolQuery{"bool": map[string]interface{}{}}
+}
+
+// add appends the queries to the clause.
+func (q BoolQuery) add(clause string, queries []map[string]interface{}) BoolQuery {
+	clauses := options(q, "bool")
+	existing, _ := clauses[clause].([]interface{})
+	clauses[clause] = append(existing, list(queries)...)
+	return q
+}
+
+// Must adds queries, that have to match and contribute to the score.
+func (q BoolQuery) Must(queries ...map[string]interface{}) BoolQuery {
+	return q.add("must", queries)
+}
+
+// Should adds queries, that should match. If the query has no must or filter
+// clauses, at least one of them has to match.
+func (q BoolQuery) Should(queries ...map[string]interface{}) BoolQuery {
+	return q.add("should", queries)
+}
+
+// Filter adds queries, that have to match, but do not contribute to the score.
+func (q BoolQuery) Filter(queries ...map[string]interface{}) BoolQuery {
+	return q.add("filter", queries)
+}
+
+// MustNot adds queries, that must not match.
+func (q BoolQuery) MustNot(queries ...map[string]interface{}) BoolQuery {
+	return q.add("must_not", queries)
+}
+
+// MinimumShouldMatch sets the number or percentage of should clauses, that have to match, e.g. 1 or "50%".
+func (q BoolQuery) MinimumShouldMatch(minimum interface{}) BoolQuery {
+	options(q, "bool")["minimum_should_match"] = minimum
+	return q
+}
+
+// Boost sets the relevance score boost of the query.
+func (q BoolQuery) Boost(boost float64) BoolQuery {
+	options(q, "bool")["boost"] = boost
+	return q
+}
+
+// NestedQuery matches documents with nested objects matching a query, see Nested.
+type NestedQuery map[string]interface{}
+
+// Nested returns a query matching documents with nested objects at path matching the query.
+func Nested(path string, query map[string]interface{}) NestedQuery {
+	return NestedQuery{"nested": map[string]interface{}{"path": path, "query": query}}
+}
+
+// ScoreMode sets how the scores of the matching nested objects are combined,
+// "avg" (default), "max", "min", "sum" or "none".
+func (q NestedQuery) ScoreMode(mode string) NestedQuery {
+	options(q, "nested")["score_mode"] = mode
+	return q
+}
+
+// IgnoreUnmapped does not return an error, if path is not mapped.
+func (q NestedQuery) IgnoreUnmapped(ignore bool) NestedQuery {
+	options(q, "nested")["ignore_unmapped"] = ignore
+	return q
+}
+
+// InnerHits returns the matching nested objects as inner hits. The options may
+// be empty or contain e.g. name, size and _source.
+func (q NestedQuery) InnerHits(innerHits map[string]interface{}) NestedQuery {
+	if innerHits == nil {
+		innerHits = map[string]interface{}{}
+	}
+	options(q, "nested")["inner_hits"] = innerHits
+	return q
+}
+
+// FunctionScoreQuery modifies the scores of the documents matching a query, see FunctionScore.
+type FunctionScoreQuery map[string]interface{}
+
+// FunctionScore returns a query modifying the scores of the documents matching
+// the query with functions. The query is optional.
+func FunctionScore(query map[string]interface{}) FunctionScoreQuery {
+	q := FunctionScoreQuery{"function_score": map[string]interface{}{}}
+	if query != nil {
+		options(q, "function_score")["query"] = query
+	}
+	return q
+}
+
+func (q FunctionScoreQuery) set(key string, value interface{}) FunctionScoreQuery {
+	options(q, "function_score")[key] = value
+	return q
+}
+
+// Function adds a score function, e.g. {"field_value_factor": {"field": "likes"}}
+// or {"random_score": {}}. The function is only applied to documents matching the
+// filter. The filter is optional.
+func (q FunctionScoreQuery) Function(filter map[string]interface{}, function map[string]interface{}) FunctionScoreQuery {
+	f := map[string]interface{}{}
+	for key, value := range function {
+		f[key] = value
+	}
+	if filter != nil {
+		f["filter"] = filter
+	}
+	functions, _ := options(q, "function_score")["functions"].([]interface{})
+	return q.set("functions", append(functions, f))
+}
+
+// Weight adds a function multiplying the score of the documents matching the filter with weight.
+func (q FunctionScoreQuery) Weight(filter map[string]interface{}, weight float64) FunctionScoreQuery {
+	return q.Function(filter, map[string]interface{}{"weight": weight})
+}
+
+// FieldValueFactor adds a function using the value of the field to compute the score.
+// The modifier is optional, e.g. "log1p" or "sqrt".
+func (q FunctionScoreQuery) FieldValueFactor(field string, factor float64, modifier string) FunctionScoreQuery {
+	params := map[string]interface{}{"field": field, "factor": factor}
+	if modifier != "" {
+		params["modifier"] = modifier
+	}
+	return q.Function(nil, map[string]interface{}{"field_value_factor": params})
+}
+
+// ScoreMode sets how the scores of the functions are combined, e.g. "multiply" (default), "sum" or "max".
+func (q FunctionScoreQuery) ScoreMode(mode string) FunctionScoreQuery {
+	return q.set("score_mode", mode)
+}
+
+// BoostMode sets how the score of the functions is combined with the score of the query,
+// e.g. "multiply" (default), "replace" or "sum".
+func (q FunctionScoreQuery) BoostMode(mode string) FunctionScoreQuery {
+	return q.set("boost_mode", mode)
+}
+
+// MaxBoost sets the maximum score of the functions.
+func (q FunctionScoreQuery) MaxBoost(max float64) FunctionScoreQuery { return q.set("max_boost", max) }
+
+// MinScore excludes documents with a lower score.
+func (q FunctionScoreQuery) MinScore(min float64) FunctionScoreQuery { return q.set("min_score", min) }
+
+// Boost sets the relevance score boost of the query.
+func (q FunctionScoreQuery) Boost(boost float64) FunctionScoreQuery { return q.set("boost", boost) }
@@ -0,0 +1,129 @@
+package query
+
+// MatchQuery is a full text query on a field, see Match.
+type MatchQuery map[string]interface{}
+
+// Match returns a full text query for the text in the field.
+func Match(field string, text interface{}) MatchQuery {
+	return MatchQuery(fieldQuery("match", field, map[string]interface{}{"query": text}))
+}
+
+func (q MatchQuery) set(key string, value interface{}) MatchQuery {
+	fieldOptions(q, "match")[key] = value
+	return q
+}
+
+// Operator sets the operator for the terms of the text, "or" (default) or "and".
+func (q MatchQuery) Operator(operator string) MatchQuery { return q.set("operator", operator) }
+
+// Fuzziness sets the allowed edit distance, e.g. "AUTO" or "1".
+func (q MatchQuery) Fuzziness(fuzziness string) MatchQuery { return q.set("fuzziness", fuzziness) }
+
+// Analyzer sets the analyzer for the text.
+func (q MatchQuery) Analyzer(analyzer string) MatchQuery { return q.set("analyzer", analyzer) }
+
+// MinimumShouldMatch sets the number or percentage of terms, that have to match, e.g. 2 or "75%".
+func (q MatchQuery) MinimumShouldMatch(minimum interface{}) MatchQuery {
+	return q.set("minimum_should_match", minimum)
+}
+
+// Boost sets the relevance score boost of the query.
+func (q MatchQuery) Boost(boost float64) MatchQuery { return q.set("boost", boost) }
+
+// MatchPhraseQuery matches a phrase in a field, see MatchPhrase.
+type MatchPhraseQuery map[string]interface{}
+
+// MatchPhrase returns a query matching the phrase in the field.
+func MatchPhrase(field, phrase string) MatchPhraseQuery {
+	return MatchPhraseQuery(fieldQuery("match_phrase", field, map[string]interface{}{"query": phrase}))
+}
+
+func (q MatchPhraseQuery) set(key string, value interface{}) MatchPhraseQuery {
+	fieldOptions(q, "match_phrase")[key] = value
+	return q
+}
+
+// Slop sets the number of allowed positions between the terms of the phrase.
+func (q MatchPhraseQuery) Slop(slop int) MatchPhraseQuery { return q.set("slop", slop) }
+
+// Analyzer sets the analyzer for the phrase.
+func (q MatchPhraseQuery) Analyzer(analyzer string) MatchPhraseQuery {
+	return q.set("analyzer", analyzer)
+}
+
+// Boost sets the relevance score boost of the query.
+func (q MatchPhraseQuery) Boost(boost float64) MatchPhraseQuery { return q.set("boost", boost) }
+
+// MultiMatchQuery is a full text query on multiple fields, see MultiMatch.
+type MultiMatchQuery map[string]interface{}
+
+// MultiMatch returns a full text query for the text in the fields. The fields
+// may contain wildcards and boosts, e.g. "title^2".
+func MultiMatch(text interface{}, fields ...string) MultiMatchQuery {
+	q := MultiMatchQuery{"multi_match": map[string]interface{}{"query": text}}
+	if len(fields) > 0 {
+		options(q, "multi_match")["fields"] = fields
+	}
+	return q
+}
+
+func (q MultiMatchQuery) set(key string, value interface{}) MultiMatchQuery {
+	options(q, "multi_match")[key] = value
+	return q
+}
+
+// Type sets how the fields are combined, e.g. "best_fields", "most_fields",
+// "cross_fields", "phrase" or "phrase_prefix".
+func (q MultiMatchQuery) Type(matchType string) MultiMatchQuery { return q.set("type", matchType) }
+
+// Operator sets the operator for the terms of the text, "or" (default) or "and".
+func (q MultiMatchQuery) Operator(operator string) MultiMatchQuery {
+	return q.set("operator", operator)
+}
+
+// Fuzziness sets the allowed edit distance, e.g. "AUTO" or "1".
+func (q MultiMatchQuery) Fuzziness(fuzziness string) MultiMatchQuery {
+	return q.set("fuzziness", fuzziness)
+}
+
+// TieBreaker sets the factor for the scores of the fields, that did not score best.
+func (q MultiMatchQuery) TieBreaker(tieBreaker float64) MultiMatchQuery {
+	return q.set("tie_breaker", tieBreaker)
+}
+
+// Boost sets the relevance score boost of the query.
+func (q MultiMatchQuery) Boost(boost float64) MultiMatchQuery { return q.set("boost", boost) }
+
+// QueryStringQuery is a query in the Lucene query syntax, see QueryString.
+type QueryStringQuery map[string]interface{}
+
+// QueryString returns a query in the Lucene query syntax, e.g. "status:active AND (title:go OR title:elasticsearch)".
+func QueryString(query string) QueryStringQuery {
+	return QueryStringQuery{"query_string": map[string]interface{}{"query": query}}
+}
+
+func (q QueryStringQuery) set(key string, value interface{}) QueryStringQuery {
+	options(q, "query_string")[key] = value
+	return q
+}
+
+// DefaultField sets the field for terms without field.
+func (q QueryStringQuery) DefaultField(field string) QueryStringQuery {
+	return q.set("default_field", field)
+}
+
+// Fields sets the fields for terms without field.
+func (q QueryStringQuery) Fields(fields ...string) QueryStringQuery { return q.set("fields", fields) }
+
+// DefaultOperator sets the operator between terms without explicit operator, "OR" (default) or "AND".
+func (q QueryStringQuery) DefaultOperator(operator string) QueryStringQuery {
+	return q.set("default_operator", operator)
+}
+
+// AnalyzeWildcard enables the analysis of terms with wildcards.
+func (q QueryStringQuery) AnalyzeWildcard(analyze bool) QueryStringQuery {
+	return q.set("analyze_wildcard", analyze)
+}
+
+// Boost sets the relevance score boost of the query.
+func (q QueryStringQuery) Boost(boost float64) QueryStringQuery { return q.set("boost", boost) }
@@ -0,0 +1,67 @@
+// Package query provides builders for the Elasticsearch query DSL.
+//
+// All queries are maps, so they can be passed to every function of the
+// elasticsearch package expecting a query map[string]interface{}:
+//
+//	q := query.Bool().
+//		Must(query.Match("title", "elasticsearch").Operator("and")).
+//		Filter(query.Range("date").Gte("now-1d"), query.Term("status", "published")).
+//		MustNot(query.Exists("deleted"))
+//	docs, total, err := client.GetDocuments("index", "doc", q, 0, 10, nil)
+//
+// The options of a query are set with its methods, which modify the query and
+// return it for chaining.
+package query
+
+// Query is a query of the query DSL.
+type Query map[string]interface{}
+
+// MatchAll returns a query matching all documents.
+func MatchAll() Query {
+	return Query{"match_all": map[string]interface{}{}}
+}
+
+// MatchNone returns a query matching no documents.
+func MatchNone() Query {
+	return Query{"match_none": map[string]interface{}{}}
+}
+
+// Exists returns a query matching documents with a value in the field.
+func Exists(field string) Query {
+	return Query{"exists": map[string]interface{}{"field": field}}
+}
+
+// IDs returns a query matching documents with one of the ids.
+func IDs(ids ...string) Query {
+	if ids == nil {
+		ids = []string{}
+	}
+	return Query{"ids": map[string]interface{}{"values": ids}}
+}
+
+// options returns the options of the query {name: options}.
+func options(q map[string]interface{}, name string) map[string]interface{} {
+	return q[name].(map[string]interface{})
+}
+
+// fieldOptions returns the options of the query {name: {field: options}}.
+func fieldOptions(q map[string]interface{}, name string) map[string]interface{} {
+	for _, value := range options(q, name) {
+		return value.(map[string]interface{})
+	}
+	return nil
+}
+
+// fieldQuery returns the query {name: {field: options}}.
+func fieldQuery(name, field string, options map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{name: map[string]interface{}{field: options}}
+}
+
+// list returns the queries as list for the query DSL.
+func list(queries []map[string]interface{}) []interface{} {
+	result := make([]interface{}, 0, len(queries))
+	for _, q := range queries {
+		result = append(result, q)
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// search accepts a query like the functions of the elasticsearch package.
+func search(query map[string]interface{}) map[string]interface{} {
+	return query
+}
+
+func TestQueries(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    map[string]interface{}
+		expected string
+	}{
+		{"match all", MatchAll(), `{"match_all":{}}`},
+		{"match none", MatchNone(), `{"match_none":{}}`},
+		{"exists", Exists("user"), `{"exists":{"field":"user"}}`},
+		{"ids", IDs("1", "2"), `{"ids":{"values":["1","2"]}}`},
+		{"ids empty", IDs(), `{"ids":{"values":[]}}`},
+		{"term", Term("status", "active").Boost(2), `{"term":{"status":{"boost":2,"value":"active"}}}`},
+		{"terms", Terms("tags", "a", 1).Boost(1.5), `{"terms":{"boost":1.5,"tags":["a",1]}}`},
+		{"range", Range("age").Gte(10).Lt(20), `{"range":{"age":{"gte":10,"lt":20}}}`},
+		{"date range", Range("date").Gt("now-1d/d").Lte("now").Format("strict_date_optional_time").TimeZone("+01:00"),
+			`{"range":{"date":{"format":"strict_date_optional_time","gt":"now-1d/d","lte":"now","time_zone":"+01:00"}}}`},
+		{"prefix", Prefix("user", "ki").Boost(2), `{"prefix":{"user":{"boost":2,"value":"ki"}}}`},
+		{"wildcard", Wildcard("user", "ki*y"), `{"wildcard":{"user":{"value":"ki*y"}}}`},
+		{"regexp", Regexp("user", "k.*y").Flags("ALL").MaxDeterminizedStates(10000),
+			`{"regexp":{"user":{"flags":"ALL","max_determinized_states":10000,"value":"k.*y"}}}`},
+		{"match", Match("message", "this is a test").Operator("and").Fuzziness("AUTO").MinimumShouldMatch("75%"),
+			`{"match":{"message":{"fuzziness":"AUTO","minimum_should_match":"75%","operator":"and","query":"this is a test"}}}`},
+		{"match phrase", MatchPhrase("message", "this is a test").Slop(2).Analyzer("standard"),
+			`{"match_phrase":{"message":{"analyzer":"standard","query":"this is a test","slop":2}}}`},
+		{"multi match", MultiMatch("quick brown fox", "title^2", "body").Type("best_fields").TieBreaker(0.3),
+			`{"multi_match":{"fields":["title^2","body"],"query":"quick brown fox","tie_breaker":0.3,"type":"best_fields"}}`},
+		{"query string", QueryString("(new york city) OR (big apple)").DefaultField("content").DefaultOperator("AND").AnalyzeWildcard(true),
+			`{"query_string":{"analyze_wildcard":true,"default_field":"content","default_operator":"AND","query":"(new york city) OR (big apple)"}}`},
+		{"bool", Bool().
+			Must(Match("title", "search")).
+			Filter(Term("status", "published"), Range("date").Gte("2015-01-01")).
+			Should(Term("tags", "a")).
+			Should(Term("tags", "b")).
+			MustNot(Exists("deleted")).
+			MinimumShouldMatch(1),
+			`{"bool":{"filter":[{"term":{"status":{"value":"published"}}},{"range":{"date":{"gte":"2015-01-01"}}}],` +
+				`"minimum_should_match":1,"must":[{"match":{"title":{"query":"search"}}}],"must_not":[{"exists":{"field":"deleted"}}],` +
+				`"should":[{"term":{"tags":{"value":"a"}}},{"term":{"tags":{"value":"b"}}}]}}`},
+		{"bool with map", Bool().Must(map[string]interface{}{"match_all": map[string]interface{}{}}), `{"bool":{"must":[{"match_all":{}}]}}`},
+		{"nested", Nested("comments", Match("comments.text", "go")).ScoreMode("max").InnerHits(nil),
+			`{"nested":{"inner_hits":{},"path":"comments","query":{"match":{"comments.text":{"query":"go"}}},"score_mode":"max"}}`},
+		{"function score", FunctionScore(Match("title", "go")).
+			Weight(Term("featured", true), 2).
+			FieldValueFactor("likes", 1.2, "log1p").
+			Function(nil, map[string]interface{}{"random_score": map[string]interface{}{}}).
+			ScoreMode("sum").BoostMode("multiply").MaxBoost(10).MinScore(1),
+			`{"function_score":{"boost_mode":"multiply","functions":[{"filter":{"term":{"featured":{"value":true}}},"weight":2},` +
+				`{"field_value_factor":{"factor":1.2,"field":"likes","modifier":"log1p"}},{"random_score":{}}],` +
+				`"max_boost":10,"min_score":1,"query":{"match":{"title":{"query":"go"}}},"score_mode":"sum"}}`},
+		{"function score without query", FunctionScore(nil).Weight(nil, 3), `{"function_score":{"functions":[{"weight":3}]}}`},
+	}
+	for _, test := range tests {
+		b, err := json.Marshal(search(test.query))
+		if err != nil {
+			t.Errorf("%s: could not marshal query: %s", test.name, err)
+			continue
+		}
+		if string(b) != test.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.name, test.expected, b)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package query
+
+// TermQuery matches documents with the exact value in a field, see Term.
+type TermQuery map[string]interface{}
+
+// Term returns a query matching documents with the exact value in the field.
+func Term(field string, value interface{}) TermQuery {
+	return TermQuery(fieldQuery("term", field, map[string]interface{}{"value": value}))
+}
+
+// Boost sets the relevance score boost of the query.
+func (q TermQuery) Boost(boost float64) TermQuery {
+	fieldOptions(q, "term")["boost"] = boost
+	return q
+}
+
+// TermsQuery matches documents with one of multiple exact values in a field, see Terms.
+type TermsQuery map[string]interface{}
+
+// Terms returns a query matching documents with one of the exact values in the field.
+func Terms(field string, values ...interface{}) TermsQuery {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return TermsQuery{"terms": map[string]interface{}{field: values}}
+}
+
+// Boost sets the relevance score boost of the query.
+func (q TermsQuery) Boost(boost float64) TermsQuery {
+	options(q, "terms")["boost"] = boost
+	return q
+}
+
+// RangeQuery matches documents with values in a range, see Range.
+type RangeQuery map[string]interface{}
+
+// Range returns a query matching documents with values of the field in a range.
+// The bounds are set with Gt, Gte, Lt and Lte.
+func Range(field string) RangeQuery {
+	return RangeQuery(fieldQuery("range", field, map[string]interface{}{}))
+}
+
+func (q RangeQuery) set(key string, value interface{}) RangeQuery {
+	fieldOptions(q, "range")[key] = value
+	return q
+}
+
+// Gt sets the exclusive lower bound.
+func (q RangeQuery) Gt(value interface{}) RangeQuery { return q.set("gt", value) }
+
+// Gte sets the inclusive lower bound.
+func (q RangeQuery) Gte(value interface{}) RangeQuery { return q.set("gte", value) }
+
+// Lt sets the exclusive upper bound.
+func (q RangeQuery) Lt(value interface{}) RangeQuery { return q.set("lt", value) }
+
+// Lte sets the inclusive upper bound.
+func (q RangeQuery) Lte(value interface{}) RangeQuery { return q.set("lte", value) }
+
+// Format sets the date format of the bounds.
+func (q RangeQuery) Format(format string) RangeQuery { return q.set("format", format) }
+
+// TimeZone sets the time zone of date bounds, e.g. "+01:00" or "Europe/Berlin".
+func (q RangeQuery) TimeZone(timeZone string) RangeQuery { return q.set("time_zone", timeZone) }
+
+// Boost sets the relevance score boost of the query.
+func (q RangeQuery) Boost(boost float64) RangeQuery { return q.set("boost", boost) }
+
+// PrefixQuery matches documents with a value starting with a prefix, see Prefix.
+type PrefixQuery map[string]interface{}
+
+// Prefix returns a query matching documents with a value of the field starting with the prefix.
+func Prefix(field, prefix string) PrefixQuery {
+	return PrefixQuery(fieldQuery("prefix", field, map[string]interface{}{"value": prefix}))
+}
+
+// Boost sets the relevance score boost of the query.
+func (q PrefixQuery) Boost(boost float64) PrefixQuery {
+	fieldOptions(q, "prefix")["boost"] = boost
+	return q
+}
+
+// WildcardQuery matches documents with a value matching a wildcard pattern, see Wildcard.
+type WildcardQuery map[string]interface{}
+
+// Wildcard returns a query matching documents with a value of the field matching
+// the pattern. The pattern supports the wildcards * and ?.
+func Wildcard(field, pattern string) WildcardQuery {
+	return WildcardQuery(fieldQuery("wildcard", field, map[string]interface{}{"value": pattern}))
+}
+
+// Boost sets the relevance score boost of the query.
+func (q WildcardQuery) Boost(boost float64) WildcardQuery {
+	fieldOptions(q, "wildcard")["boost"] = boost
+	return q
+}
+
+// RegexpQuery matches documents with a value matching a regular expression, see Regexp.
+type RegexpQuery map[string]interface{}
+
+// Regexp returns a query matching documents with a value of the field matching
+// the regular expression.
+func Regexp(field, regexp string) RegexpQuery {
+	return RegexpQuery(fieldQuery("regexp", field, map[string]interface{}{"value": regexp}))
+}
+
+// Flags sets the enabled operators of the regular expression, e.g. "ALL" or "INTERSECTION|COMPLEMENT".
+func (q RegexpQuery) Flags(flags string) RegexpQuery {
+	fieldOptions(q, "regexp")["flags"] = flags
+	return q
+}
+
+// MaxDeterminizedStates sets the maximum number of automaton states of the regular expression.
+func (q RegexpQuery) MaxDeterminizedStates(states int) RegexpQuery {
+	fieldOptions(q, "regexp")["max_determinized_states"] = states
+	return q
+}
+
+// Boost sets the relevance score boost of the query.
+func (q RegexpQuery) Boost(boost float64) RegexpQuery {
+	fieldOptions(q, "regexp")["boost"] = boost
+	return q
+}
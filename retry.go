@@ -0,0 +1,115 @@
+package elasticsearch
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy defines which failed requests are retried and how long to wait
+// between the attempts. Zero values are replaced by the defaults of DefaultRetryPolicy.
+//
+// Requests rejected with 429 Too Many Requests were not executed by Elasticsearch
+// and are always retried. Other retryable status codes and connection errors after
+// the request was sent are only retried for idempotent requests, unless RetryNonIdempotent
+// is set. Reads, deletes and writes of documents with an id are idempotent, but not
+// searches with POST, creates, writes with WriteOptions conditions and snapshots.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	// Set it to 1 to disable retries.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry. The time doubles
+	// with every further retry, a random jitter of up to 50 percent is subtracted.
+	InitialBackoff time.Duration
+	// MaxBackoff limits the time to wait before a retry.
+	MaxBackoff time.Duration
+	// RetryOnStatus contains the http status codes of retryable responses.
+	RetryOnStatus []int
+	// RetryNonIdempotent enables retries of all requests, e.g. of POST requests for
+	// bulk imports that can safely be repeated because all documents have an id.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the retry policy used if Config.RetryPolicy is not set.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		RetryOnStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// withDefaults returns a copy of the policy with all zero values replaced by defaults.
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	result := DefaultRetryPolicy()
+	if p == nil {
+		return result
+	}
+	if p.MaxAttempts > 0 {
+		result.MaxAttempts = p.MaxAttempts
+	}
+	if p.InitialBackoff > 0 {
+		result.InitialBackoff = p.InitialBackoff
+	}
+	if p.MaxBackoff > 0 {
+		result.MaxBackoff = p.MaxBackoff
+	}
+	if p.RetryOnStatus != nil {
+		result.RetryOnStatus = p.RetryOnStatus
+	}
+	result.RetryNonIdempotent = p.RetryNonIdempotent
+	return result
+}
+
+// retryStatus returns true, if a response with the status code can be retried.
+func (p *RetryPolicy) retryStatus(idempotent bool, statusCode int) bool {
+	for _, status := range p.RetryOnStatus {
+		if status == statusCode {
+			return statusCode == http.StatusTooManyRequests || p.retryRequest(idempotent)
+		}
+	}
+	return false
+}
+
+// retryRequest returns true, if a request that may have been executed can be retried.
+func (p *RetryPolicy) retryRequest(idempotent bool) bool {
+	return p.RetryNonIdempotent || idempotent
+}
+
+// backoff returns the time to wait after the failed attempt. If the response
+// contains a Retry-After header, its value is used instead.
+func (p *RetryPolicy) backoff(attempt int, header http.Header) time.Duration {
+	if wait, ok := retryAfter(header); ok {
+		return wait
+	}
+	wait := p.MaxBackoff
+	if shift := uint(attempt - 1); shift < 32 && p.InitialBackoff<<shift > 0 && p.InitialBackoff<<shift < p.MaxBackoff {
+		wait = p.InitialBackoff << shift
+	}
+	return wait - time.Duration(rand.Int63n(int64(wait)/2+1))
+}
+
+// retryAfter parses the Retry-After header, which contains either seconds or a http date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
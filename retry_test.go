@@ -0,0 +1,268 @@
+package elasticsearch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer returns a test server answering with the given status codes in order
+// and with 200 OK after all status codes were used.
+func statusServer(header http.Header, statusCodes ...int) (*httptest.Server, *int32) {
+	var requests int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&requests, 1)) - 1
+		if i < len(statusCodes) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statusCodes[i])
+			w.Write([]byte(`{"error":"failed"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})), &requests
+}
+
+func fastRetryPolicy(maxAttempts int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func TestClient_RetryStatus(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		policy   *RetryPolicy
+		statuses []int
+		post     bool
+		success  bool
+		requests int32
+	}{
+		{"throttled get", fastRetryPolicy(3), []int{429, 429}, false, true, 3},
+		{"throttled post", fastRetryPolicy(3), []int{429}, true, true, 2},
+		{"too many attempts", fastRetryPolicy(3), []int{429, 429, 429}, false, false, 3},
+		{"unavailable get", fastRetryPolicy(3), []int{503, 502, 504}, false, false, 3},
+		{"unavailable get retried", fastRetryPolicy(4), []int{503, 502, 504}, false, true, 4},
+		{"unavailable post", fastRetryPolicy(3), []int{503}, true, false, 1},
+		{"unavailable post retried", &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryNonIdempotent: true}, []int{503}, true, true, 2},
+		{"not retryable", fastRetryPolicy(3), []int{500}, false, false, 1},
+		{"custom status", &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryOnStatus: []int{500}}, []int{500}, false, true, 2},
+		{"disabled", fastRetryPolicy(1), []int{429}, false, false, 1},
+	} {
+		server, requests := statusServer(nil, test.statuses...)
+		client, err := NewClient(Config{URLs: []string{server.URL}, RetryPolicy: test.policy})
+		if err != nil {
+			t.Fatalf("%s: could not open client: %s", test.name, err)
+		}
+		if test.post {
+			err = client.Refresh("testclient_retrystatus")
+		} else {
+			err = client.Ping()
+		}
+		server.Close()
+		if test.success && err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+		if !test.success && err == nil {
+			t.Fatalf("%s: expected error", test.name)
+		}
+		if *requests != test.requests {
+			t.Fatalf("%s: expected %d requests, got: %d", test.name, test.requests, *requests)
+		}
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	server, _ := statusServer(http.Header{"Retry-After": {"1"}}, http.StatusTooManyRequests)
+	defer server.Close()
+	client, err := NewClient(Config{URLs: []string{server.URL}, RetryPolicy: fastRetryPolicy(2)})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	start := time.Now()
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Retry-After was not honored, retried after %s", elapsed)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for _, test := range []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{100, time.Second},
+	} {
+		for i := 0; i < 10; i++ {
+			wait := policy.backoff(test.attempt, http.Header{})
+			if wait < test.max/2 || wait > test.max {
+				t.Fatalf("attempt %d: expected backoff between %s and %s, got: %s", test.attempt, test.max/2, test.max, wait)
+			}
+		}
+	}
+	date := time.Now().Add(3 * time.Second).UTC().Format(http.TimeFormat)
+	if wait := policy.backoff(1, http.Header{"Retry-After": {date}}); wait < time.Second || wait > 3*time.Second {
+		t.Fatalf("expected backoff from Retry-After date, got: %s", wait)
+	}
+}
+
+// replayServer answers the first request with 429 Too Many Requests and records
+// all requests.
+type replayServer struct {
+	*httptest.Server
+	requests []string
+}
+
+func newReplayServer(response string) *replayServer {
+	s := &replayServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		if len(s.requests) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	return s
+}
+
+func TestClient_RetrySameRequest(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		response string
+		call     func(c *Client) error
+	}{
+		{"insert", `{}`, func(c *Client) error {
+			return c.InsertDocument("testclient_retry", "doc", "1", map[string]interface{}{"field1": "value1"}, RefreshTrue)
+		}},
+		{"bulk", `{"items":[]}`, func(c *Client) error {
+			_, err := c.InsertDocuments("testclient_retry", "doc", map[string]map[string]interface{}{"1": {"field1": "value1"}})
+			return err
+		}},
+		{"update", `{}`, func(c *Client) error {
+			return c.UpdateDocument("testclient_retry", "doc", "1", "ctx._source.field1 = params.value", map[string]interface{}{"value": "valueX"}, RefreshFalse)
+		}},
+		{"delete", `{}`, func(c *Client) error {
+			return c.DeleteDocument("testclient_retry", "doc", "1", RefreshFalse)
+		}},
+		{"delete by query", `{}`, func(c *Client) error {
+			return c.DeleteDocuments("testclient_retry", "doc", map[string]interface{}{"match_all": map[string]interface{}{}}, RefreshFalse)
+		}},
+		{"clear scroll", `{}`, func(c *Client) error {
+			return c.deleteScroll(context.Background(), "scroll-1")
+		}},
+		{"search", `{"hits":{"total":0,"hits":[]}}`, func(c *Client) error {
+			_, _, err := c.GetDocuments("testclient_retry", "doc", map[string]interface{}{"match_all": map[string]interface{}{}}, 0, 10, nil)
+			return err
+		}},
+	} {
+		server := newReplayServer(test.response)
+		client, err := NewClient(Config{URLs: []string{server.URL}, RetryPolicy: fastRetryPolicy(2)})
+		if err != nil {
+			t.Fatalf("%s: could not open client: %s", test.name, err)
+		}
+		err = test.call(client)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+		if len(server.requests) != 2 {
+			t.Fatalf("%s: expected 2 requests, got: %d", test.name, len(server.requests))
+		}
+		if server.requests[0] != server.requests[1] {
+			t.Fatalf("%s: retried request differs\noriginal: %s\nretried:  %s", test.name, server.requests[0], server.requests[1])
+		}
+		t.Logf("%s: %s", test.name, server.requests[1])
+	}
+}
+
+func TestClient_RetryIdempotent(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		call     func(c *Client) error
+		requests int32
+	}{
+		{"insert", func(c *Client) error {
+			return c.InsertDocument("testclient_retryidempotent", "doc", "1", map[string]interface{}{}, RefreshFalse)
+		}, 2},
+		{"conditional insert", func(c *Client) error {
+			_, err := c.InsertDocumentWithOptions("testclient_retryidempotent", "doc", "1", map[string]interface{}{}, WriteOptions{IfSeqNo: 1, IfPrimaryTerm: 1})
+			return err
+		}, 1},
+		{"external version", func(c *Client) error {
+			_, err := c.InsertDocumentWithOptions("testclient_retryidempotent", "doc", "1", map[string]interface{}{}, WriteOptions{Version: 2, VersionType: VersionTypeExternal})
+			return err
+		}, 1},
+		{"conditional delete", func(c *Client) error {
+			_, err := c.DeleteDocumentWithOptions("testclient_retryidempotent", "doc", "1", WriteOptions{IfSeqNo: 1, IfPrimaryTerm: 1})
+			return err
+		}, 1},
+		{"create", func(c *Client) error {
+			_, err := c.CreateDocument("testclient_retryidempotent", "doc", "1", map[string]interface{}{}, RefreshFalse)
+			return err
+		}, 1},
+		{"snapshot", func(c *Client) error {
+			return c.AddSnapshot("repo", "snap")
+		}, 1},
+	} {
+		server, requests := statusServer(nil, http.StatusBadGateway)
+		client, err := NewClient(Config{URLs: []string{server.URL}, RetryPolicy: fastRetryPolicy(3)})
+		if err != nil {
+			t.Fatalf("%s: could not open client: %s", test.name, err)
+		}
+		err = test.call(client)
+		server.Close()
+		if test.requests == 1 && err == nil {
+			t.Fatalf("%s: expected error", test.name)
+		}
+		if *requests != test.requests {
+			t.Fatalf("%s: expected %d requests, got: %d", test.name, test.requests, *requests)
+		}
+	}
+}
+
+func TestClient_RetryTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{URLs: []string{server.URL}, Timeout: 50 * time.Millisecond, RetryPolicy: fastRetryPolicy(3)})
+	if err != nil {
+		t.Fatalf("could not create client: %s", err)
+	}
+	if err := client.AddSnapshot("repo", "snap"); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expected the snapshot to be sent once, got %d requests", n)
+	}
+	if failures := client.pool.nodes[0].failures; failures != 0 {
+		t.Fatalf("expected the slow node to be alive, got %d failures", failures)
+	}
+	atomic.StoreInt32(&requests, 0)
+	if _, err := client.get(context.Background(), "", nil); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("expected the get to be retried, got %d requests", n)
+	}
+}
@@ -0,0 +1,125 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SearchResponse is the response of a search. The _source of the hits is
+// unmarshaled into T.
+type SearchResponse[T any] struct {
+	Took     int64           `json:"took"`
+	TimedOut bool            `json:"timed_out"`
+	Shards   ShardStatistics `json:"_shards"`
+	Hits     SearchHits[T]   `json:"hits"`
+	// Aggregations contains the raw results of the aggregations by name,
+	// use Aggregation to decode them.
+	Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+	// ScrollID is set, if the search was started with a scroll.
+	ScrollID string `json:"_scroll_id,omitempty"`
+}
+
+// Aggregation decodes the result of the aggregation with the name into v.
+func (r *SearchResponse[T]) Aggregation(name string, v interface{}) error {
+	b, ok := r.Aggregations[name]
+	if !ok {
+		return fmt.Errorf("aggregation %s not found in response", name)
+	}
+	if err := decodeResponse(b, v); err != nil {
+		return fmt.Errorf("could not decode aggregation %s: %w", name, err)
+	}
+	return nil
+}
+
+// SearchHits contains the hits of a search.
+type SearchHits[T any] struct {
+	Total TotalHits `json:"total"`
+	// MaxScore is nil, if the hits were not scored, e.g. because of a sort.
+	MaxScore *float64 `json:"max_score"`
+	Hits     []Hit[T] `json:"hits"`
+}
+
+// InnerHits contains the inner hits of a search hit. Their _source is kept as raw JSON,
+// because it usually is a part of the document.
+type InnerHits struct {
+	Hits SearchHits[json.RawMessage] `json:"hits"`
+}
+
+// Relations of TotalHits
+const (
+	// TotalHitsEqual means that the total is accurate.
+	TotalHitsEqual = "eq"
+	// TotalHitsGreaterOrEqual means that the total is a lower bound, see track_total_hits.
+	TotalHitsGreaterOrEqual = "gte"
+)
+
+// TotalHits is the total number of hits of a search. Elasticsearch 6 returns the
+// total as number, Elasticsearch 7 and later as object with value and relation.
+// Both formats are accepted.
+type TotalHits struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation"`
+}
+
+// UnmarshalJSON is the interface implementation for json Unmarshaler.
+func (t *TotalHits) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 0 && b[0] != '{' {
+		if err := json.Unmarshal(b, &t.Value); err != nil {
+			return fmt.Errorf("could not decode total hits: %w", err)
+		}
+		t.Relation = TotalHitsEqual
+		return nil
+	}
+	type totalHits TotalHits
+	return json.Unmarshal(b, (*totalHits)(t))
+}
+
+// ShardStatistics contains the number of shards, that executed a request.
+type ShardStatistics struct {
+	Total      int            `json:"total"`
+	Successful int            `json:"successful"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	Failures   []ShardFailure `json:"failures,omitempty"`
+}
+
+// ShardFailure describes why a request failed on a shard.
+type ShardFailure struct {
+	Shard  int        `json:"shard"`
+	Index  string     `json:"index"`
+	Node   string     `json:"node"`
+	Reason ErrorCause `json:"reason"`
+}
+
+// Search executes the search request in a specific index and returns the response
+// with the _source of the hits unmarshaled into T. The request is the body of the search,
+// e.g. query, sort, from, size, highlight and aggs. The hits contain the version and,
+// since Elasticsearch 6.7, the sequence number and the primary term.
+func Search[T any](c *Client, index, doctype string, request map[string]interface{}) (*SearchResponse[T], error) {
+	return SearchContext[T](context.Background(), c, index, doctype, request)
+}
+
+// SearchContext is like Search, but aborts the search when ctx is done.
+func SearchContext[T any](ctx context.Context, c *Client, index, doctype string, request map[string]interface{}) (*SearchResponse[T], error) {
+	ctx, span := c.startOperation(ctx, APISearch, "Search", index, doctype)
+	defer span.End()
+	b, err := json.Marshal(c.typedSearchRequest(request))
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal query: %w", err)
+	}
+	b, err = c.post(ctx, c.indexPath(index, doctype)+"/_search", b)
+	if err != nil {
+		return nil, fmt.Errorf("could not search documents: %w", err)
+	}
+	result := &SearchResponse[T]{}
+	if err := decodeResponse(b, result); err != nil {
+		return nil, fmt.Errorf("could not decode search response: %w", err)
+	}
+	return result, nil
+}
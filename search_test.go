@@ -0,0 +1,132 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NextronSystems/go-elasticsearch/estest"
+	"github.com/NextronSystems/go-elasticsearch/query"
+)
+
+func TestTotalHits_UnmarshalJSON(t *testing.T) {
+	tests := map[string]TotalHits{
+		`42`:                               {Value: 42, Relation: TotalHitsEqual},
+		`{"value":10000,"relation":"gte"}`: {Value: 10000, Relation: TotalHitsGreaterOrEqual},
+		`{"value":3,"relation":"eq"}`:      {Value: 3, Relation: TotalHitsEqual},
+		`null`:                             {},
+	}
+	for input, expected := range tests {
+		var total TotalHits
+		if err := json.Unmarshal([]byte(input), &total); err != nil {
+			t.Errorf("%s: could not unmarshal: %s", input, err)
+			continue
+		}
+		if total != expected {
+			t.Errorf("%s: expected %+v, got %+v", input, expected, total)
+		}
+	}
+	if err := json.Unmarshal([]byte(`"many"`), &TotalHits{}); err == nil {
+		t.Error("expected error for string total")
+	}
+}
+
+func TestSearchResponse_UnmarshalJSON(t *testing.T) {
+	body := `{
+		"took": 5, "timed_out": false,
+		"_shards": {"total": 2, "successful": 1, "skipped": 0, "failed": 1,
+			"failures": [{"shard": 1, "index": "test", "node": "n1", "reason": {"type": "query_shard_exception", "reason": "failed"}}]},
+		"hits": {"total": {"value": 1, "relation": "eq"}, "max_score": null, "hits": [{
+			"_index": "test", "_id": "1", "_score": null, "_source": {"name": "alpha", "count": 1},
+			"sort": [1, "alpha"],
+			"highlight": {"name": ["<em>alpha</em>"]},
+			"inner_hits": {"comments": {"hits": {"total": 1, "max_score": 1.5, "hits": [{"_id": "1", "_score": 1.5, "_source": {"text": "hi"}}]}}}
+		}]},
+		"aggregations": {"counts": {"value": 1}}
+	}`
+	var result SearchResponse[typedDocument]
+	if err := decodeResponse([]byte(body), &result); err != nil {
+		t.Fatalf("could not decode response: %s", err)
+	}
+	if result.Took != 5 || result.Shards.Failed != 1 || result.Shards.Failures[0].Reason.Type != "query_shard_exception" {
+		t.Fatalf("unexpected statistics: %+v", result)
+	}
+	if result.Hits.MaxScore != nil || result.Hits.Total.Value != 1 {
+		t.Fatalf("unexpected hits: %+v", result.Hits)
+	}
+	hit := result.Hits.Hits[0]
+	if hit.Source.Name != "alpha" || len(hit.Sort) != 2 || hit.Sort[1] != "alpha" || hit.Highlight["name"][0] != "<em>alpha</em>" {
+		t.Fatalf("unexpected hit: %+v", hit)
+	}
+	inner := hit.InnerHits["comments"].Hits
+	if inner.Total.Value != 1 || *inner.MaxScore != 1.5 || string(inner.Hits[0].Source) != `{"text": "hi"}` {
+		t.Fatalf("unexpected inner hits: %+v", inner)
+	}
+	var counts struct {
+		Value int `json:"value"`
+	}
+	if err := result.Aggregation("counts", &counts); err != nil || counts.Value != 1 {
+		t.Fatalf("unexpected aggregation: %v %+v", err, counts)
+	}
+	if err := result.Aggregation("missing", &counts); err == nil {
+		t.Fatal("expected error for missing aggregation")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	forEachVersion(t, fakeVersions, func(t *testing.T, server *estest.Server, client *Client) {
+		for i, name := range []string{"alpha", "beta", "gamma"} {
+			if _, err := Index(client, "search", "doc", name, typedDocument{Name: name, Count: i}, RefreshTrue); err != nil {
+				t.Fatalf("could not index document: %s", err)
+			}
+		}
+		result, err := Search[typedDocument](client, "search", "doc", map[string]interface{}{
+			"sort": []interface{}{map[string]interface{}{"count": "desc"}},
+			"size": 2,
+			"aggs": map[string]interface{}{"max": map[string]interface{}{"max": map[string]interface{}{"field": "count"}}},
+		})
+		if err != nil {
+			t.Fatalf("could not search: %s", err)
+		}
+		if result.Hits.Total != (TotalHits{Value: 3, Relation: TotalHitsEqual}) || len(result.Hits.Hits) != 2 || result.Hits.MaxScore != nil {
+			t.Fatalf("unexpected hits: %+v", result.Hits)
+		}
+		if hit := result.Hits.Hits[0]; hit.ID != "gamma" || hit.Sort[0] != json.Number("2") || hit.Version != 1 {
+			t.Fatalf("unexpected first hit: %+v", hit)
+		}
+		var max struct {
+			Value float64 `json:"value"`
+		}
+		if err := result.Aggregation("max", &max); err != nil || max.Value != 2 {
+			t.Fatalf("unexpected aggregation: %v %+v", err, max)
+		}
+	})
+}
+
+func TestSearch_QueryBuilder(t *testing.T) {
+	const index = "testclient_querybuilder"
+	documentClient.DeleteIndex(index)
+	for i, name := range []string{"alpha", "beta", "gamma"} {
+		if _, err := Index(documentClient, index, "doc", name, typedDocument{Name: name, Count: i}, RefreshTrue); err != nil {
+			t.Fatalf("could not index document: %s", err)
+		}
+	}
+	q := query.Bool().
+		Filter(query.Range("count").Gte(1)).
+		MustNot(query.Term("name", "gamma"))
+	docs, total, err := documentClient.GetDocuments(index, "doc", q, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("could not get documents: %s", err)
+	}
+	if total != 1 || docs[0]["_id"] != "beta" {
+		t.Fatalf("unexpected documents: %d %v", total, docs)
+	}
+	result, err := Search[typedDocument](documentClient, index, "doc", map[string]interface{}{
+		"query": query.Bool().Should(query.IDs("alpha"), query.Prefix("name", "ga")),
+	})
+	if err != nil {
+		t.Fatalf("could not search: %s", err)
+	}
+	if result.Hits.Total.Value != 2 {
+		t.Fatalf("expected 2 hits, got %+v", result.Hits)
+	}
+}
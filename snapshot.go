@@ -1,6 +1,7 @@
 package elasticsearch
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -9,6 +10,13 @@ import (
 // is configured in /etc/elasticsearch/elasticsearch.yml before calling
 // this function.
 func (c *Client) AddRepository(name string, location string) error {
+	return c.AddRepositoryContext(context.Background(), name, location)
+}
+
+// AddRepositoryContext is like AddRepository, but aborts the request when ctx is done.
+func (c *Client) AddRepositoryContext(ctx context.Context, name string, location string) error {
+	ctx, span := c.startOperation(ctx, APISnapshot, "AddRepository", "", "")
+	defer span.End()
 	b, err := json.Marshal(map[string]interface{}{
 		"type": "fs",
 		"settings": map[string]interface{}{
@@ -18,13 +26,22 @@ func (c *Client) AddRepository(name string, location string) error {
 	if err != nil {
 		return err
 	}
-	_, err = c.put(fmt.Sprintf("_snapshot/%s", name), b)
+	_, err = c.put(ctx, fmt.Sprintf("_snapshot/%s", name), b, true)
 	return err
 }
 
 // AddSnapshot adds a new snapshot in a specified repository. Ensure that
 // the repository exists before calling this function.
 func (c *Client) AddSnapshot(repositoryName string, snapshotName string) error {
-	_, err := c.put(fmt.Sprintf("_snapshot/%s/%s?wait_for_completion=true", repositoryName, snapshotName), nil)
+	return c.AddSnapshotContext(context.Background(), repositoryName, snapshotName)
+}
+
+// AddSnapshotContext is like AddSnapshot, but stops waiting for the snapshot to complete
+// when ctx is done. The snapshot itself is not aborted by Elasticsearch in this case.
+func (c *Client) AddSnapshotContext(ctx context.Context, repositoryName string, snapshotName string) error {
+	ctx, span := c.startOperation(ctx, APISnapshot, "AddSnapshot", "", "")
+	defer span.End()
+	// A second request fails, because the snapshot already exists.
+	_, err := c.put(ctx, fmt.Sprintf("_snapshot/%s/%s?wait_for_completion=true", repositoryName, snapshotName), nil, false)
 	return err
 }
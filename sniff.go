@@ -0,0 +1,130 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sniffTimeout is the maximum time a single sniff request may take.
+const sniffTimeout = time.Second * 10
+
+// startSniffer starts the goroutine that updates the node pool periodically and,
+// if enabled, after connection failures. The first sniff is done immediately.
+func (c *Client) startSniffer(interval time.Duration) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), sniffTimeout)
+			if err := c.sniff(ctx); err != nil {
+				log.Infof("could not sniff Elasticsearch nodes: %s", err)
+			}
+			cancel()
+			select {
+			case <-tick:
+			case <-c.sniffTrigger:
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+}
+
+// triggerSniff requests a sniff after a connection failure without blocking.
+// Multiple triggers while a sniff is pending result in a single sniff.
+func (c *Client) triggerSniff() {
+	if c.sniffTrigger == nil {
+		return
+	}
+	select {
+	case c.sniffTrigger <- struct{}{}:
+	default:
+	}
+}
+
+// sniff asks the seed nodes one after another for all HTTP enabled nodes of the
+// cluster and replaces the nodes of the pool with the result.
+func (c *Client) sniff(ctx context.Context) error {
+	var err error
+	for _, seed := range c.seeds {
+		var urls []*url.URL
+		if urls, err = c.sniffNode(ctx, seed); err != nil {
+			continue
+		}
+		if len(urls) == 0 {
+			return fmt.Errorf("node %s returned no http enabled nodes", seed)
+		}
+		c.pool.update(urls)
+		return nil
+	}
+	return err
+}
+
+// sniffNode returns the urls of all HTTP enabled nodes known by the node 'seed'.
+func (c *Client) sniffNode(ctx context.Context, seed *url.URL) ([]*url.URL, error) {
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/_nodes/http", seed.String()), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.statusCode != http.StatusOK {
+		return nil, newElasticsearchError(res.statusCode, res.body)
+	}
+	result := struct {
+		Nodes map[string]struct {
+			HTTP *struct {
+				PublishAddress string `json:"publish_address"`
+			} `json:"http"`
+		} `json:"nodes"`
+	}{}
+	if err := json.Unmarshal(res.body, &result); err != nil {
+		return nil, fmt.Errorf("could not decode nodes: %w", err)
+	}
+	var urls []*url.URL
+	for id, n := range result.Nodes {
+		if n.HTTP == nil || n.HTTP.PublishAddress == "" {
+			continue
+		}
+		u, err := publishAddressURL(seed, n.HTTP.PublishAddress)
+		if err != nil {
+			log.Infof("skipping node %s: %s", id, err)
+			continue
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// publishAddressURL converts the publish address of a node into its base url.
+// Elasticsearch returns the address as 'ip:port' or, if the node has
+// a hostname, as 'hostname/ip:port'. The hostname is preferred, so that
+// certificates can be verified. The scheme, credentials and path prefix are
+// taken from the seed url.
+func publishAddressURL(seed *url.URL, address string) (*url.URL, error) {
+	host := address
+	if i := strings.Index(address, "/"); i >= 0 {
+		_, port, err := net.SplitHostPort(address[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid publish address %s: %w", address, err)
+		}
+		host = net.JoinHostPort(address[:i], port)
+	} else if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid publish address %s: %w", address, err)
+	}
+	return &url.URL{Scheme: seed.Scheme, User: seed.User, Host: host, Path: seed.Path}, nil
+}
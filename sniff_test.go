@@ -0,0 +1,151 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nodesServer returns a test server answering _nodes/http with the given publish addresses.
+func nodesServer(addresses func() []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_nodes/http") {
+			w.Write([]byte(`{}`))
+			return
+		}
+		var nodes []string
+		for i, address := range addresses() {
+			nodes = append(nodes, fmt.Sprintf(`"node%d":{"http":{"publish_address":%q}}`, i, address))
+		}
+		nodes = append(nodes, `"master":{"roles":["master"]}`)
+		fmt.Fprintf(w, `{"nodes":{%s}}`, strings.Join(nodes, ","))
+	}))
+}
+
+func poolURLs(c *Client) []string {
+	c.pool.mu.Lock()
+	defer c.pool.mu.Unlock()
+	var urls []string
+	for _, n := range c.pool.nodes {
+		urls = append(urls, n.url.String())
+	}
+	return urls
+}
+
+func TestClient_Sniff(t *testing.T) {
+	var counter int32
+	node1 := countingServer(&counter)
+	defer node1.Close()
+	node2 := countingServer(&counter)
+	defer node2.Close()
+	var mu sync.Mutex
+	addresses := []string{strings.TrimPrefix(node1.URL, "http://"), "localhost/" + strings.TrimPrefix(node2.URL, "http://")}
+	seed := nodesServer(func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return addresses
+	})
+	defer seed.Close()
+	client, err := Open(seed.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.sniff(context.Background()); err != nil {
+		t.Fatalf("could not sniff: %s", err)
+	}
+	_, port2, _ := strings.Cut(strings.TrimPrefix(node2.URL, "http://"), ":")
+	urls := poolURLs(client)
+	sort.Strings(urls)
+	if len(urls) != 2 || urls[0] != node1.URL || urls[1] != "http://localhost:"+port2 {
+		t.Fatalf("unexpected nodes after sniffing: %v", urls)
+	}
+	mu.Lock()
+	addresses = addresses[:1]
+	mu.Unlock()
+	if err := client.sniff(context.Background()); err != nil {
+		t.Fatalf("could not sniff: %s", err)
+	}
+	if urls := poolURLs(client); len(urls) != 1 || urls[0] != node1.URL {
+		t.Fatalf("expected node to be removed, got: %v", urls)
+	}
+}
+
+func TestClient_SniffSeedURL(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{}`))
+	}))
+	defer node.Close()
+	seed := nodesServer(func() []string {
+		return []string{strings.TrimPrefix(node.URL, "http://")}
+	})
+	defer seed.Close()
+	client, err := Open(strings.Replace(seed.URL, "http://", "http://elastic:secret@", 1) + "/prefix")
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.sniff(context.Background()); err != nil {
+		t.Fatalf("could not sniff: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	r := <-requests
+	if user, password, ok := r.BasicAuth(); !ok || user != "elastic" || password != "secret" {
+		t.Fatalf("expected the credentials of the seed url, got %q %q", user, password)
+	}
+	if r.URL.Path != "/prefix/" {
+		t.Fatalf("expected the path prefix of the seed url, got %s", r.URL.Path)
+	}
+}
+
+func TestClient_SniffOnFailure(t *testing.T) {
+	var counter int32
+	node := countingServer(&counter)
+	defer node.Close()
+	dead := countingServer(&counter)
+	dead.Close()
+	var mu sync.Mutex
+	address := strings.TrimPrefix(dead.URL, "http://")
+	seed := nodesServer(func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return []string{address}
+	})
+	defer seed.Close()
+	client, err := NewClient(Config{
+		URLs:           []string{seed.URL},
+		SniffOnFailure: true,
+		RetryPolicy:    &RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	defer client.Close()
+	waitForNodes := func(expected string) {
+		for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+			if urls := poolURLs(client); len(urls) == 1 && urls[0] == expected {
+				return
+			}
+		}
+		t.Fatalf("expected node %s, got: %v", expected, poolURLs(client))
+	}
+	waitForNodes(dead.URL)
+	mu.Lock()
+	address = strings.TrimPrefix(node.URL, "http://")
+	mu.Unlock()
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected connection error")
+	}
+	waitForNodes(node.URL)
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping sniffed node: %s", err)
+	}
+}
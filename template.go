@@ -1,29 +1,83 @@
 package elasticsearch
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"path"
 )
 
-// AddTemplate adds a new template to Elasticsearch.
+// AddTemplate adds a new template to Elasticsearch. Since Elasticsearch 7.8, the template
+// is added as composable index template. Legacy templates with top level settings, mappings
+// and aliases are converted automatically. Mappings must not contain a mapping type on
+// Elasticsearch 7 and newer.
 func (c *Client) AddTemplate(id string, template map[string]interface{}) error {
+	return c.AddTemplateContext(context.Background(), id, template)
+}
+
+// AddTemplateContext is like AddTemplate, but aborts the request when ctx is done.
+func (c *Client) AddTemplateContext(ctx context.Context, id string, template map[string]interface{}) error {
+	ctx, span := c.startOperation(ctx, APITemplate, "AddTemplate", "", "")
+	defer span.End()
+	if c.composableTemplates() {
+		template = composableTemplate(template)
+	}
 	b, err := json.Marshal(template)
 	if err != nil {
-		return fmt.Errorf("could not marshal template: %s", err)
+		return fmt.Errorf("could not marshal template: %w", err)
 	}
-	apipath := path.Join("_template", id)
-	if _, err := c.put(apipath, b); err != nil {
-		return fmt.Errorf("could not add template: %s", err)
+	apipath := c.templatePath(id)
+	if _, err := c.put(ctx, apipath, b, true); err != nil {
+		return fmt.Errorf("could not add template: %w", err)
 	}
 	return nil
 }
 
 // DeleteTemplate deletes a template.
 func (c *Client) DeleteTemplate(id string) error {
-	apipath := path.Join("_template", id)
-	if _, err := c.delete_(apipath, nil); err != nil {
-		return fmt.Errorf("could not delete template: %s", err)
+	return c.DeleteTemplateContext(context.Background(), id)
+}
+
+// DeleteTemplateContext is like DeleteTemplate, but aborts the request when ctx is done.
+func (c *Client) DeleteTemplateContext(ctx context.Context, id string) error {
+	ctx, span := c.startOperation(ctx, APITemplate, "DeleteTemplate", "", "")
+	defer span.End()
+	apipath := c.templatePath(id)
+	if _, err := c.delete_(ctx, apipath, nil, true); err != nil {
+		return fmt.Errorf("could not delete template: %w", err)
 	}
 	return nil
 }
+
+// templatePath returns the path of the template api for the detected version.
+func (c *Client) templatePath(id string) string {
+	if c.composableTemplates() {
+		return path.Join("_index_template", id)
+	}
+	return path.Join("_template", id)
+}
+
+// composableTemplate converts a legacy template into a composable index template by
+// moving settings, mappings and aliases into the 'template' field. The order of a legacy
+// template becomes the priority.
+func composableTemplate(template map[string]interface{}) map[string]interface{} {
+	if _, ok := template["template"].(map[string]interface{}); ok {
+		return template
+	}
+	result := map[string]interface{}{}
+	inner := map[string]interface{}{}
+	for key, value := range template {
+		switch key {
+		case "settings", "mappings", "aliases":
+			inner[key] = value
+		case "order":
+			result["priority"] = value
+		default:
+			result[key] = value
+		}
+	}
+	if len(inner) > 0 {
+		result["template"] = inner
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// tlsConfig returns the TLS configuration for the settings in config.
+// If no TLS setting is configured, nil is returned and the defaults are used.
+func tlsConfig(config Config) (*tls.Config, error) {
+	if config.CACert == nil && config.ClientCert == nil && config.ClientKey == nil && config.CertificateFingerprint == "" {
+		return nil, nil
+	}
+	result := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CACert != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(config.CACert) {
+			return nil, errors.New("could not parse ca certificate: no valid pem certificate found")
+		}
+		result.RootCAs = pool
+	}
+	if config.ClientCert != nil || config.ClientKey != nil {
+		cert, err := tls.X509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		result.Certificates = []tls.Certificate{cert}
+	}
+	if config.CertificateFingerprint != "" {
+		fingerprint, err := parseFingerprint(config.CertificateFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		// The chain is verified by the fingerprint instead of a certificate authority.
+		result.InsecureSkipVerify = true
+		result.VerifyConnection = verifyFingerprint(fingerprint)
+	}
+	return result, nil
+}
+
+// useTLSConfig configures the transport with the TLS configuration. If the
+// configuration verifies the connections itself, they are established by dialTLS.
+func useTLSConfig(transport *http.Transport, config *tls.Config) {
+	transport.TLSClientConfig = config
+	if config.VerifyConnection != nil {
+		transport.DialTLSContext = dialTLS(transport.DialContext, config)
+	}
+}
+
+// dialTLS returns a function, that establishes TLS connections and passes the
+// dialed host to VerifyConnection. Go does not send IP addresses as server name,
+// so the connection state has no server name for nodes addressed by IP otherwise.
+func dialTLS(dial func(ctx context.Context, network, addr string) (net.Conn, error), config *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		connConfig := config.Clone()
+		if connConfig.ServerName == "" {
+			connConfig.ServerName = host
+		}
+		connConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if state.ServerName == "" {
+				state.ServerName = connConfig.ServerName
+			}
+			return config.VerifyConnection(state)
+		}
+		tlsConn := tls.Client(conn, connConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// verifyFingerprint returns a function, that verifies the server certificate chain
+// with the certificate matching the fingerprint as the only root. The server sends
+// the pinned certificate authority in every handshake, so it is not enough, that
+// it is part of the chain, the leaf has to be signed by it as well.
+func verifyFingerprint(fingerprint []byte) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		certs := state.PeerCertificates
+		for i, cert := range certs {
+			sum := sha256.Sum256(cert.Raw)
+			if !bytes.Equal(sum[:], fingerprint) {
+				continue
+			}
+			if i == 0 {
+				return nil
+			}
+			if state.ServerName == "" {
+				return errors.New("could not verify server certificate with the configured fingerprint: host name is unknown")
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+			intermediates := x509.NewCertPool()
+			for _, intermediate := range certs[1:i] {
+				intermediates.AddCert(intermediate)
+			}
+			if _, err := certs[0].Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				DNSName:       state.ServerName,
+			}); err != nil {
+				return fmt.Errorf("could not verify server certificate with the configured fingerprint: %w", err)
+			}
+			return nil
+		}
+		return errors.New("no server certificate matches the configured fingerprint")
+	}
+}
+
+// parseFingerprint decodes a hex encoded SHA-256 fingerprint. Colons and
+// upper case letters, as printed by openssl, are accepted.
+func parseFingerprint(s string) ([]byte, error) {
+	fingerprint, err := hex.DecodeString(strings.ToLower(strings.Replace(s, ":", "", -1)))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode certificate fingerprint: %w", err)
+	}
+	if len(fingerprint) != sha256.Size {
+		return nil, fmt.Errorf("certificate fingerprint has %d bytes, expected a sha256 fingerprint", len(fingerprint))
+	}
+	return fingerprint, nil
+}
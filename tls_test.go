@@ -0,0 +1,210 @@
+package elasticsearch
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	stdlog "log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSServer(clientAuth tls.ClientAuthType) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: clientAuth}
+	server.Config.ErrorLog = stdlog.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	return server
+}
+
+func certificatePEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// newClientCertificate creates a self-signed client certificate and returns it PEM encoded.
+func newClientCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal key: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestClient_TLS(t *testing.T) {
+	server := newTLSServer(tls.NoClientCert)
+	defer server.Close()
+	sum := sha256.Sum256(server.Certificate().Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	var opensslFingerprint []string
+	for _, b := range sum {
+		opensslFingerprint = append(opensslFingerprint, fmt.Sprintf("%02X", b))
+	}
+	wrongFingerprint := hex.EncodeToString(make([]byte, sha256.Size))
+	for _, test := range []struct {
+		name    string
+		config  Config
+		success bool
+	}{
+		{"system pool", Config{}, false},
+		{"ca cert", Config{CACert: certificatePEM(server.Certificate())}, true},
+		{"fingerprint", Config{CertificateFingerprint: fingerprint}, true},
+		{"openssl fingerprint", Config{CertificateFingerprint: strings.Join(opensslFingerprint, ":")}, true},
+		{"wrong fingerprint", Config{CertificateFingerprint: wrongFingerprint}, false},
+	} {
+		test.config.URLs = []string{server.URL}
+		client, err := NewClient(test.config)
+		if err != nil {
+			t.Fatalf("%s: could not open client: %s", test.name, err)
+		}
+		err = client.Ping()
+		if test.success && err != nil {
+			t.Fatalf("%s: could not ping: %s", test.name, err)
+		}
+		if !test.success && err == nil {
+			t.Fatalf("%s: expected certificate error", test.name)
+		}
+	}
+}
+
+// newCertificate creates a certificate for the template signed by the parent or
+// self-signed, if parent is nil.
+func newCertificate(t *testing.T, template *x509.Certificate, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %s", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("could not create certificate: %s", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %s", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestClient_TLSFingerprintChain(t *testing.T) {
+	ca := newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	server := func(t *testing.T, parent *tls.Certificate, ip net.IP) tls.Certificate {
+		return newCertificate(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "node"},
+			IPAddresses: []net.IP{ip},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}, parent)
+	}
+	sum := sha256.Sum256(ca.Leaf.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	for _, test := range []struct {
+		name    string
+		cert    tls.Certificate
+		success bool
+	}{
+		{"issued by ca", server(t, &ca, net.IPv4(127, 0, 0, 1)), true},
+		// The ca certificate is public, an attacker can send it after its own certificate.
+		{"appended ca", server(t, nil, net.IPv4(127, 0, 0, 1)), false},
+		// The certificate of another node issued by the ca is not valid for this node.
+		{"other node", server(t, &ca, net.IPv4(10, 0, 0, 1)), false},
+	} {
+		test.cert.Certificate = append(test.cert.Certificate, ca.Leaf.Raw)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{}`))
+		}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{test.cert}}
+		server.StartTLS()
+		client, err := NewClient(Config{URLs: []string{server.URL}, CertificateFingerprint: fingerprint})
+		if err != nil {
+			t.Fatalf("%s: could not open client: %s", test.name, err)
+		}
+		err = client.Ping()
+		server.Close()
+		if test.success && err != nil {
+			t.Fatalf("%s: could not ping: %s", test.name, err)
+		}
+		if !test.success && err == nil {
+			t.Fatalf("%s: expected certificate error", test.name)
+		}
+	}
+}
+
+func TestClient_TLSClientCertificate(t *testing.T) {
+	server := newTLSServer(tls.RequireAnyClientCert)
+	defer server.Close()
+	client, err := NewClient(Config{URLs: []string{server.URL}, CACert: certificatePEM(server.Certificate())})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected error without client certificate")
+	}
+	cert, key := newClientCertificate(t)
+	client, err = NewClient(Config{
+		URLs:       []string{server.URL},
+		CACert:     certificatePEM(server.Certificate()),
+		ClientCert: cert,
+		ClientKey:  key,
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping with client certificate: %s", err)
+	}
+}
+
+func TestClient_TLSInvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{CACert: []byte("no pem")},
+		{ClientCert: []byte("no pem")},
+		{CertificateFingerprint: "abc"},
+		{CertificateFingerprint: "zz"},
+	} {
+		config.URLs = []string{"https://localhost:9200"}
+		if _, err := NewClient(config); err == nil {
+			t.Fatalf("expected error for config %#v", config)
+		}
+	}
+}
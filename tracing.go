@@ -0,0 +1,100 @@
+package elasticsearch
+
+import (
+	"context"
+	"strings"
+)
+
+// Tracer creates spans for the operations of a Client. Implement it to connect
+// the client with a tracing library like OpenTelemetry.
+//
+// Every operation, e.g. InsertDocument, is a span with one child span per request
+// sent to Elasticsearch. Operations with multiple requests, like ScrollDocuments or
+// CompositeAggregate, have one child span per page.
+type Tracer interface {
+	// StartSpan starts a new span as child of the span in ctx, if any, and returns
+	// a context containing the new span.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation or request.
+type Span interface {
+	// SetAttribute sets an attribute like the index or the http status code.
+	SetAttribute(key string, value interface{})
+	// RecordError marks the span as failed.
+	RecordError(err error)
+	// TraceParent returns the value of the W3C traceparent header, that propagates
+	// the trace context of the span to Elasticsearch. If empty, the header is omitted.
+	TraceParent() string
+	// End completes the span.
+	End()
+}
+
+// Span attributes set by the client
+const (
+	AttributeDBSystem           = "db.system"
+	AttributeIndex              = "elasticsearch.index"
+	AttributeDoctype            = "elasticsearch.doctype"
+	AttributeRetries            = "elasticsearch.retries"
+	AttributeHTTPMethod         = "http.method"
+	AttributeHTTPStatusCode     = "http.status_code"
+	AttributeRequestBodyLength  = "http.request_content_length"
+	AttributeResponseBodyLength = "http.response_content_length"
+)
+
+// discardTracer will be used as default tracer and creates spans that do nothing.
+type discardTracer struct{}
+
+func (discardTracer) StartSpan(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, discardSpan{}
+}
+
+type discardSpan struct{}
+
+func (discardSpan) SetAttribute(_ string, _ interface{}) {}
+
+func (discardSpan) RecordError(_ error) {}
+
+func (discardSpan) TraceParent() string { return "" }
+
+func (discardSpan) End() {}
+
+// operation contains information about the client operation all requests belong to.
+type operation struct {
+	api  API
+	span Span
+}
+
+type operationKey struct{}
+
+// startOperation starts the span for the client operation 'name' and returns
+// a context assigning all requests to the operation. The caller has to end
+// the returned span.
+func (c *Client) startOperation(ctx context.Context, api API, name string, index, doctype string) (context.Context, Span) {
+	ctx, span := c.tracer.StartSpan(ctx, name)
+	span.SetAttribute(AttributeDBSystem, "elasticsearch")
+	if index != "" {
+		span.SetAttribute(AttributeIndex, index)
+	}
+	if doctype != "" {
+		span.SetAttribute(AttributeDoctype, doctype)
+	}
+	return context.WithValue(ctx, operationKey{}, &operation{api: api, span: span}), span
+}
+
+// operationFromContext returns the operation started by startOperation. By default,
+// the requests are assigned to APICluster without span.
+func operationFromContext(ctx context.Context) *operation {
+	if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+		return op
+	}
+	return &operation{api: APICluster, span: discardSpan{}}
+}
+
+// startRequestSpan starts the span for a single request of an operation.
+func (c *Client) startRequestSpan(ctx context.Context, method string) (context.Context, Span) {
+	ctx, span := c.tracer.StartSpan(ctx, strings.ToUpper(method))
+	span.SetAttribute(AttributeDBSystem, "elasticsearch")
+	span.SetAttribute(AttributeHTTPMethod, method)
+	return ctx, span
+}
@@ -0,0 +1,175 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingTracer records all spans in the order they were started.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	tracer     *recordingTracer
+	id         int
+	parent     *recordingSpan
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+type recordingSpanKey struct{}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	parent, _ := ctx.Value(recordingSpanKey{}).(*recordingSpan)
+	span := &recordingSpan{tracer: r, id: len(r.spans) + 1, parent: parent, name: name, attributes: map[string]interface{}{}}
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.err = err
+}
+
+func (s *recordingSpan) TraceParent() string {
+	return fmt.Sprintf("00-0af7651916cd43dd8448eb211c80319c-%016x-01", s.id)
+}
+
+func (s *recordingSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+// tree returns the names of all spans indented by their depth.
+func (r *recordingTracer) tree() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []string
+	for _, span := range r.spans {
+		depth := 0
+		for parent := span.parent; parent != nil; parent = parent.parent {
+			depth++
+		}
+		result = append(result, strings.Repeat("  ", depth)+span.name)
+	}
+	return result
+}
+
+func TestClient_Tracer(t *testing.T) {
+	var mu sync.Mutex
+	var traceParents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceParents = append(traceParents, r.Header.Get("traceparent"))
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/testclient_tracer/doc/1":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"testclient_tracer","_id":"1","found":false}`))
+		case r.URL.Path == "/testclient_tracer/doc/_search":
+			w.Write([]byte(`{"_scroll_id":"1","hits":{"hits":[{"_id":"1"}]}}`))
+		case r.URL.Path == "/_search/scroll" && r.Method == http.MethodPost:
+			w.Write([]byte(`{"_scroll_id":"1","hits":{"hits":[]}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	tracer := &recordingTracer{}
+	client, err := NewClient(Config{URLs: []string{server.URL}, Tracer: tracer})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if _, err := client.GetDocument("testclient_tracer", "doc", "1"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+	docs := make(chan map[string]interface{}, 10)
+	if err := client.ScrollDocuments("testclient_tracer", "doc", nil, docs); err != nil {
+		t.Fatalf("could not scroll documents: %s", err)
+	}
+
+	expected := []string{
+		"GetDocument",
+		"  GET",
+		"ScrollDocuments",
+		"  POST",
+		"  POST",
+	}
+	if tree := tracer.tree(); strings.Join(tree, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected spans:\n%s\nexpected:\n%s", strings.Join(tree, "\n"), strings.Join(expected, "\n"))
+	}
+	for _, span := range tracer.spans {
+		if !span.ended {
+			t.Errorf("span %s was not ended", span.name)
+		}
+		if span.attributes[AttributeDBSystem] != "elasticsearch" {
+			t.Errorf("span %s has no db.system attribute", span.name)
+		}
+	}
+
+	get, request := tracer.spans[0], tracer.spans[1]
+	if get.attributes[AttributeIndex] != "testclient_tracer" || get.attributes[AttributeDoctype] != "doc" {
+		t.Errorf("unexpected operation attributes: %v", get.attributes)
+	}
+	if !IsNotFound(get.err) || !IsNotFound(request.err) {
+		t.Errorf("expected not found errors on spans, got: %v, %v", get.err, request.err)
+	}
+	if request.attributes[AttributeHTTPMethod] != http.MethodGet || request.attributes[AttributeHTTPStatusCode] != http.StatusNotFound {
+		t.Errorf("unexpected request attributes: %v", request.attributes)
+	}
+	if _, ok := request.attributes[AttributeResponseBodyLength]; !ok {
+		t.Errorf("response body length is missing: %v", request.attributes)
+	}
+	if tracer.spans[2].err != nil {
+		t.Errorf("unexpected error on successful operation: %v", tracer.spans[2].err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(traceParents) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(traceParents))
+	}
+	for i, traceParent := range traceParents {
+		if expected := []int{2, 4, 5}[i]; traceParent != tracer.spans[expected-1].TraceParent() {
+			t.Errorf("request %d has traceparent %q, expected the one of span %d", i, traceParent, expected)
+		}
+	}
+}
+
+func TestClient_TracerRetries(t *testing.T) {
+	server, _ := statusServer(nil, http.StatusServiceUnavailable)
+	defer server.Close()
+	tracer := &recordingTracer{}
+	client, err := NewClient(Config{URLs: []string{server.URL}, Tracer: tracer, RetryPolicy: fastRetryPolicy(3)})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("could not ping: %s", err)
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", tracer.tree())
+	}
+	if retries := tracer.spans[1].attributes[AttributeRetries]; retries != 1 {
+		t.Fatalf("expected 1 retry, got %v", retries)
+	}
+}
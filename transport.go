@@ -0,0 +1,55 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultTransport is shared by all clients without custom transport or TLS settings,
+// so that connections are reused across clients.
+var defaultTransport = newDefaultTransport()
+
+// newDefaultTransport returns a transport tuned for many concurrent requests to few
+// hosts, like bulk imports. Only connecting is limited in time, because requests
+// like snapshots or updates by query may take very long. Use Config.Timeout or
+// a context to limit the duration of requests.
+func newDefaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// newHTTPClient returns the http client used for all requests of a Client.
+func newHTTPClient(config Config) (*http.Client, error) {
+	tlsClientConfig, err := tlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := config.Transport
+	switch {
+	case transport != nil && tlsClientConfig != nil:
+		return nil, errors.New("tls settings can not be used with a custom transport, configure tls in the transport instead")
+	case tlsClientConfig != nil:
+		t := newDefaultTransport()
+		useTLSConfig(t, tlsClientConfig)
+		transport = t
+	case transport == nil:
+		transport = defaultTransport
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}, nil
+}
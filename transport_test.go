@@ -0,0 +1,101 @@
+package elasticsearch
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc is a http.RoundTripper test double.
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestClient_Transport(t *testing.T) {
+	var requests []string
+	client, err := NewClient(Config{
+		URLs: []string{"http://elasticsearch:9200"},
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests = append(requests, r.Method+" "+r.URL.String())
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"status":"green"}`)),
+				Request:    r,
+			}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	health, err := client.Health()
+	if err != nil {
+		t.Fatalf("could not get health: %s", err)
+	}
+	if health != StatusGreen {
+		t.Fatalf("expected health green, got: %s", health)
+	}
+	if len(requests) != 1 || requests[0] != "GET http://elasticsearch:9200/_cluster/health" {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
+
+func TestClient_TransportWithTLS(t *testing.T) {
+	_, err := NewClient(Config{
+		URLs:                   []string{"https://localhost:9200"},
+		Transport:              http.DefaultTransport,
+		CertificateFingerprint: strings.Repeat("00", 32),
+	})
+	if err == nil {
+		t.Fatal("expected error for tls settings with custom transport")
+	}
+}
+
+func TestClient_ConnectionReuse(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := client.Ping(); err != nil {
+			t.Fatalf("could not ping: %s", err)
+		}
+	}
+	if connections != 1 {
+		t.Fatalf("expected 1 connection, got: %d", connections)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(Config{URLs: []string{server.URL}, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected timeout")
+	} else {
+		t.Logf("error as expected: %s", err)
+	}
+}
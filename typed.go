@@ -0,0 +1,242 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Hit is a document with its metadata. The _source of the document is
+// unmarshaled into Source.
+type Hit[T any] struct {
+	ID          string  `json:"_id"`
+	Index       string  `json:"_index"`
+	Version     int64   `json:"_version"`
+	SeqNo       int64   `json:"_seq_no"`
+	PrimaryTerm int64   `json:"_primary_term"`
+	Score       float64 `json:"_score"`
+	Source      T       `json:"_source"`
+	// Sort contains the sort values of a search hit.
+	Sort []interface{} `json:"sort,omitempty"`
+	// Highlight contains the highlighted fragments of a search hit.
+	Highlight map[string][]string `json:"highlight,omitempty"`
+	// InnerHits contains the inner hits of a search hit by name.
+	InnerHits map[string]InnerHits `json:"inner_hits,omitempty"`
+}
+
+// IfMatch returns write options, that only write, if the document was not changed
+// since it was read. Hits of Elasticsearch before 6.7 have no sequence number,
+// their version is compared instead. For newer versions, a write with the options
+// fails, if the hit has no sequence number, e.g. because it was read before the
+// version was detected by Ping or Info.
+func (h *Hit[T]) IfMatch(refresh Refresh) WriteOptions {
+	return ifMatch(refresh, h.Version, h.SeqNo, h.PrimaryTerm)
+}
+
+// decodeResponse decodes the response body into v. Numbers in interface{} values
+// are decoded as json.Number, like in the untyped apis.
+func decodeResponse(b []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// Get returns the document with the id in a specific index, with the source
+// unmarshaled into T.
+func Get[T any](c *Client, index, doctype, id string) (*Hit[T], error) {
+	return GetContext[T](context.Background(), c, index, doctype, id)
+}
+
+// GetContext is like Get, but aborts the request when ctx is done.
+func GetContext[T any](ctx context.Context, c *Client, index, doctype, id string) (*Hit[T], error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "Get", index, doctype)
+	defer span.End()
+	b, err := c.get(ctx, c.documentPath(index, doctype, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not get document: %w", err)
+	}
+	hit := &Hit[T]{}
+	if err := decodeResponse(b, hit); err != nil {
+		return nil, fmt.Errorf("could not decode document: %w", err)
+	}
+	return hit, nil
+}
+
+// Index inserts the document with the id in a specific index, like InsertDocument.
+// The returned hit contains the document and the metadata of the new version.
+func Index[T any](c *Client, index, doctype, id string, document T, refresh Refresh) (*Hit[T], error) {
+	return IndexContext(context.Background(), c, index, doctype, id, document, refresh)
+}
+
+// IndexContext is like Index, but aborts the request when ctx is done.
+func IndexContext[T any](ctx context.Context, c *Client, index, doctype, id string, document T, refresh Refresh) (*Hit[T], error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "Index", index, doctype)
+	defer span.End()
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the document: %w", err)
+	}
+	apipath := c.documentPath(index, doctype, id) + "?refresh=" + getRefreshString(refresh)
+	b, err = c.put(ctx, apipath, b, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not insert document: %w", err)
+	}
+	hit := &Hit[T]{}
+	if err := decodeResponse(b, hit); err != nil {
+		return nil, fmt.Errorf("could not decode result: %w", err)
+	}
+	hit.Source = document
+	return hit, nil
+}
+
+// typedSearchRequest returns a copy of the search request, that additionally
+// requests the metadata of the Hit.
+func (c *Client) typedSearchRequest(request map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{
+		"version": true,
+	}
+	if c.Version().AtLeast(6, 7) {
+		result["seq_no_primary_term"] = true
+	}
+	for key, value := range request {
+		result[key] = value
+	}
+	return result
+}
+
+// ScrollIterator iterates over all documents of a scroll, see Scroll.
+type ScrollIterator[T any] struct {
+	client   *Client
+	ctx      context.Context
+	span     Span
+	apipath  string
+	request  map[string]interface{}
+	scrollID string
+	hits     []Hit[T]
+	hit      *Hit[T]
+	closed   bool
+	err      error
+}
+
+// Scroll returns an iterator over all documents in a specific index matching the query,
+// like ScrollDocuments. A query is optional. The documents are fetched in pages while iterating:
+//
+//	it := elasticsearch.Scroll[MyDocument](client, "index", "doc", nil)
+//	defer it.Close()
+//	for it.Next() {
+//		hit := it.Hit()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func Scroll[T any](c *Client, index, doctype string, query map[string]interface{}) *ScrollIterator[T] {
+	return ScrollContext[T](context.Background(), c, index, doctype, query)
+}
+
+// ScrollContext is like Scroll, but stops scrolling when ctx is done. In this case,
+// Next returns false and Err returns ctx.Err().
+func ScrollContext[T any](ctx context.Context, c *Client, index, doctype string, query map[string]interface{}) *ScrollIterator[T] {
+	ctx, span := c.startOperation(ctx, APIScroll, "Scroll", index, doctype)
+	request := c.typedSearchRequest(map[string]interface{}{
+		"size": 1000,
+		"sort": []string{"_doc"},
+	})
+	if query != nil {
+		request["query"] = query
+	}
+	return &ScrollIterator[T]{
+		client:  c,
+		ctx:     ctx,
+		span:    span,
+		apipath: c.indexPath(index, doctype) + "/_search?scroll=5m",
+		request: request,
+	}
+}
+
+// Next advances the iterator to the next document. It returns false, if there
+// are no more documents or an error occurred.
+func (it *ScrollIterator[T]) Next() bool {
+	for len(it.hits) == 0 {
+		if it.closed || it.err != nil {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			it.Close()
+			return false
+		}
+	}
+	it.hit = &it.hits[0]
+	it.hits = it.hits[1:]
+	return true
+}
+
+// Hit returns the current document.
+func (it *ScrollIterator[T]) Hit() *Hit[T] {
+	return it.hit
+}
+
+// Err returns the error, that stopped the iteration.
+func (it *ScrollIterator[T]) Err() error {
+	return it.err
+}
+
+// Close clears the scroll in Elasticsearch. It is called automatically after the
+// last document, but has to be called if the iteration is stopped early.
+func (it *ScrollIterator[T]) Close() error {
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+	it.hits = nil
+	defer it.span.End()
+	if it.scrollID == "" {
+		return nil
+	}
+	if err := it.client.clearScroll(it.ctx, it.scrollID); err != nil {
+		it.span.RecordError(err)
+		return fmt.Errorf("could not delete scroll: %w", err)
+	}
+	return nil
+}
+
+// fetch loads the next page of the scroll.
+func (it *ScrollIterator[T]) fetch() error {
+	if err := it.ctx.Err(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(it.request)
+	if err != nil {
+		return fmt.Errorf("could not marshal scroll request: %w", err)
+	}
+	b, err = it.client.post(it.ctx, it.apipath, b)
+	if err != nil {
+		return fmt.Errorf("could not scroll documents: %w", err)
+	}
+	result := struct {
+		ScrollID string `json:"_scroll_id"`
+		Hits     struct {
+			Hits []Hit[T] `json:"hits"`
+		} `json:"hits"`
+	}{}
+	if err := decodeResponse(b, &result); err != nil {
+		return fmt.Errorf("could not unmarshal scroll result: %w", err)
+	}
+	if it.scrollID != "" && it.scrollID != result.ScrollID {
+		if err := it.client.deleteScroll(it.ctx, it.scrollID); err != nil {
+			return fmt.Errorf("could not delete scroll: %w", err)
+		}
+	}
+	it.scrollID = result.ScrollID
+	it.apipath = "_search/scroll"
+	it.request = map[string]interface{}{
+		"scroll":    "5m",
+		"scroll_id": result.ScrollID,
+	}
+	if len(result.Hits.Hits) == 0 {
+		return it.Close()
+	}
+	it.hits = result.Hits.Hits
+	return nil
+}
@@ -0,0 +1,135 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type typedDocument struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestTyped_IndexGetSearch(t *testing.T) {
+	const index = "testclient_typed"
+	documentClient.DeleteIndex(index)
+	hit, err := Index(documentClient, index, "doc", "1", typedDocument{Name: "alpha", Count: 1}, RefreshTrue)
+	if err != nil {
+		t.Fatalf("could not index document: %s", err)
+	}
+	if hit.ID != "1" || hit.Index != index || hit.Version != 1 || hit.Source.Name != "alpha" {
+		t.Fatalf("unexpected index result: %+v", hit)
+	}
+	if _, err := Index(documentClient, index, "doc", "2", typedDocument{Name: "beta", Count: 2}, RefreshTrue); err != nil {
+		t.Fatalf("could not index document: %s", err)
+	}
+	hit, err = Get[typedDocument](documentClient, index, "doc", "2")
+	if err != nil {
+		t.Fatalf("could not get document: %s", err)
+	}
+	if hit.ID != "2" || hit.Version != 1 || hit.Source != (typedDocument{Name: "beta", Count: 2}) {
+		t.Fatalf("unexpected document: %+v", hit)
+	}
+	if _, err := Get[typedDocument](documentClient, index, "doc", "3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	result, err := Search[typedDocument](documentClient, index, "doc", map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{"count": map[string]interface{}{"gte": 2}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not search documents: %s", err)
+	}
+	hits := result.Hits.Hits
+	if result.Hits.Total.Value != 1 || len(hits) != 1 || hits[0].ID != "2" || hits[0].Source.Name != "beta" || hits[0].Version != 1 || hits[0].PrimaryTerm != 1 {
+		t.Fatalf("unexpected search result: %+v", result.Hits)
+	}
+}
+
+func TestTyped_Scroll(t *testing.T) {
+	const index = "testclient_typed_scroll"
+	documentClient.DeleteIndex(index)
+	docs := map[string]map[string]interface{}{}
+	for i := 0; i < 2500; i++ {
+		docs[strconv.Itoa(i)] = map[string]interface{}{"name": "doc", "count": i}
+	}
+	if _, err := documentClient.InsertDocuments(index, "doc", docs); err != nil {
+		t.Fatalf("could not insert documents: %s", err)
+	}
+	if err := documentClient.Refresh(index); err != nil {
+		t.Fatalf("could not refresh: %s", err)
+	}
+	it := Scroll[typedDocument](documentClient, index, "doc", nil)
+	defer it.Close()
+	var counts []int
+	for it.Next() {
+		counts = append(counts, it.Hit().Source.Count)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("could not scroll: %s", err)
+	}
+	sort.Ints(counts)
+	if len(counts) != 2500 || counts[0] != 0 || counts[2499] != 2499 {
+		t.Fatalf("expected 2500 documents, got %d", len(counts))
+	}
+
+	it = Scroll[typedDocument](documentClient, index, "doc", map[string]interface{}{
+		"range": map[string]interface{}{"count": map[string]interface{}{"lt": 1500}},
+	})
+	if !it.Next() {
+		t.Fatalf("expected documents: %v", it.Err())
+	}
+	if err := it.Close(); err != nil {
+		t.Fatalf("could not close scroll: %s", err)
+	}
+	if it.Next() {
+		t.Fatal("expected no documents after close")
+	}
+}
+
+func TestTyped_ScrollCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"_scroll_id":"1","hits":{"hits":[{"_id":"1","_source":{"count":1}}]}}`))
+	}))
+	defer server.Close()
+	tracer := &recordingTracer{}
+	client, err := NewClient(Config{URLs: []string{server.URL}, Tracer: tracer})
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	it := ScrollContext[typedDocument](ctx, client, "testclient_typed_scroll", "doc", nil)
+	if !it.Next() {
+		t.Fatalf("expected documents: %v", it.Err())
+	}
+	cancel()
+	if it.Next() {
+		t.Fatal("expected no documents after cancel")
+	}
+	if err := it.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got: %v", err)
+	}
+	// the scroll is cleared in the operation, although its context is canceled
+	expected := []string{
+		"Scroll",
+		"  POST",
+		"  DELETE",
+	}
+	if tree := tracer.tree(); strings.Join(tree, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected spans:\n%s\nexpected:\n%s", strings.Join(tree, "\n"), strings.Join(expected, "\n"))
+	}
+	if err := tracer.spans[2].err; err != nil {
+		t.Fatalf("could not clear scroll: %s", err)
+	}
+}
@@ -0,0 +1,90 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of an Elasticsearch cluster.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// parseVersion parses version numbers like 7.10.2 or 8.0.0-SNAPSHOT.
+func parseVersion(s string) (Version, error) {
+	var result Version
+	parts := strings.SplitN(strings.SplitN(s, "-", 2)[0], ".", 3)
+	numbers := []*int{&result.Major, &result.Minor, &result.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("could not parse version %q", s)
+		}
+		*numbers[i] = n
+	}
+	return result, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast returns true, if the version is equal to or newer than major.minor.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Major > major || v.Major == major && v.Minor >= minor
+}
+
+// Version returns the version of Elasticsearch detected by Ping or Info. It is the
+// zero Version, if the version was not detected yet.
+func (c *Client) Version() Version {
+	c.versionMu.RLock()
+	defer c.versionMu.RUnlock()
+	return c.version
+}
+
+func (c *Client) setVersion(v Version) {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	c.version = v
+}
+
+// typeless returns true, if Elasticsearch 7 or newer was detected. These versions
+// don't support mapping types in the document apis.
+func (c *Client) typeless() bool {
+	return c.Version().Major >= 7
+}
+
+// indexPath returns the path for apis like _search or _bulk in an index. The
+// doctype is only used for Elasticsearch 6.
+func (c *Client) indexPath(index, doctype string) string {
+	if c.typeless() {
+		return index
+	}
+	return path.Join(index, doctype)
+}
+
+// documentPath returns the path of a single document.
+func (c *Client) documentPath(index, doctype, id string) string {
+	if c.typeless() {
+		return path.Join(index, "_doc", id)
+	}
+	return path.Join(index, doctype, id)
+}
+
+// updatePath returns the path of the update api for a single document.
+func (c *Client) updatePath(index, doctype, id string) string {
+	if c.typeless() {
+		return path.Join(index, "_update", id)
+	}
+	return path.Join(index, doctype, id, "_update")
+}
+
+// composableTemplates returns true, if the _index_template api is available,
+// which replaces the legacy _template api since Elasticsearch 7.8.
+func (c *Client) composableTemplates() bool {
+	return c.Version().AtLeast(7, 8)
+}
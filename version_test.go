@@ -0,0 +1,221 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected Version
+		valid    bool
+	}{
+		{"6.1.1", Version{6, 1, 1}, true},
+		{"7.10.2", Version{7, 10, 2}, true},
+		{"8.0.0-SNAPSHOT", Version{8, 0, 0}, true},
+		{"8.1", Version{8, 1, 0}, true},
+		{"", Version{}, false},
+		{"seven", Version{}, false},
+	}
+	for _, test := range tests {
+		version, err := parseVersion(test.version)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: unexpected error: %v", test.version, err)
+			continue
+		}
+		if version != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.version, test.expected, version)
+		}
+	}
+}
+
+// versionServer answers the root endpoint with the version and records all other requests.
+type versionServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+}
+
+func newVersionServer(version string) *versionServer {
+	s := &versionServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"` + version + `"},"tagline":"You Know, for Search"}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		switch {
+		case strings.Contains(r.URL.Path, "_bulk"):
+			w.Write([]byte(`{"items":[]}`))
+		default:
+			w.Write([]byte(`{"hits":{"total":0,"hits":[]}}`))
+		}
+	}))
+	return s
+}
+
+func TestClient_VersionPaths(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected []string
+	}{
+		{"6.8.0", []string{
+			"PUT /index/doc/1?refresh=false",
+			"GET /index/doc/1",
+			"POST /index/doc/1/_update?refresh=false",
+			"DELETE /index/doc/1?refresh=false",
+			"GET /index/doc/_search?from=0&size=10",
+			"POST /index/doc/_update_by_query?conflicts=proceed&refresh=false",
+			"POST /index/doc/_delete_by_query?refresh=false",
+			"PUT /index/doc/_bulk",
+			"GET /index/doc/_search",
+			"PUT /_template/template",
+		}},
+		{"7.10.2", []string{
+			"PUT /index/_doc/1?refresh=false",
+			"GET /index/_doc/1",
+			"POST /index/_update/1?refresh=false",
+			"DELETE /index/_doc/1?refresh=false",
+			"GET /index/_search?from=0&size=10&rest_total_hits_as_int=true",
+			"POST /index/_update_by_query?conflicts=proceed&refresh=false",
+			"POST /index/_delete_by_query?refresh=false",
+			"PUT /index/_bulk",
+			"GET /index/_search",
+			"PUT /_index_template/template",
+		}},
+		{"8.11.0", []string{
+			"PUT /index/_doc/1?refresh=false",
+			"GET /index/_doc/1",
+			"POST /index/_update/1?refresh=false",
+			"DELETE /index/_doc/1?refresh=false",
+			"GET /index/_search?from=0&size=10&rest_total_hits_as_int=true",
+			"POST /index/_update_by_query?conflicts=proceed&refresh=false",
+			"POST /index/_delete_by_query?refresh=false",
+			"PUT /index/_bulk",
+			"GET /index/_search",
+			"PUT /_index_template/template",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			server := newVersionServer(test.version)
+			defer server.Close()
+			client, err := Open(server.URL)
+			if err != nil {
+				t.Fatalf("could not open client: %s", err)
+			}
+			if err := client.Ping(); err != nil {
+				t.Fatalf("could not ping: %s", err)
+			}
+			if client.Version().String() != test.version {
+				t.Fatalf("expected version %s, got %s", test.version, client.Version())
+			}
+			client.InsertDocument("index", "doc", "1", map[string]interface{}{"field": "value"}, RefreshFalse)
+			client.GetDocument("index", "doc", "1")
+			client.UpdateDocument("index", "doc", "1", "ctx._source.field = params.value", map[string]interface{}{"value": 1}, RefreshFalse)
+			client.DeleteDocument("index", "doc", "1", RefreshFalse)
+			client.GetDocuments("index", "doc", nil, 0, 10, nil)
+			client.UpdateDocuments("index", "doc", nil, "ctx._source.field = 1", nil, RefreshFalse)
+			client.DeleteDocuments("index", "doc", nil, RefreshFalse)
+			client.InsertDocuments("index", "doc", map[string]map[string]interface{}{"1": {"field": "value"}})
+			client.CardinalityAggregate("index", "doc", nil, "field")
+			client.AddTemplate("template", map[string]interface{}{"index_patterns": []string{"*"}})
+			if !reflect.DeepEqual(server.requests, test.expected) {
+				t.Fatalf("unexpected requests:\n%s\nexpected:\n%s", strings.Join(server.requests, "\n"), strings.Join(test.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestClient_VersionUnknown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("ping without version should succeed: %s", err)
+	}
+	if client.Version() != (Version{}) {
+		t.Fatalf("expected unknown version, got %s", client.Version())
+	}
+	if apipath := client.documentPath("index", "doc", "1"); apipath != "index/doc/1" {
+		t.Fatalf("expected elasticsearch 6 path, got %s", apipath)
+	}
+}
+
+func TestClient_VersionDateHistogram(t *testing.T) {
+	tests := []struct {
+		version  string
+		interval DateHistogramInterval
+		expected map[string]interface{}
+	}{
+		{"6.8.0", DateHistogramIntervalDay, map[string]interface{}{"field": "date", "interval": "day"}},
+		{"7.1.0", DateHistogramIntervalSecond, map[string]interface{}{"field": "date", "interval": "second"}},
+		{"7.2.0", DateHistogramIntervalDay, map[string]interface{}{"field": "date", "calendar_interval": "day"}},
+		{"8.0.0", DateHistogramIntervalSecond, map[string]interface{}{"field": "date", "fixed_interval": "1s"}},
+	}
+	for _, test := range tests {
+		server := newVersionServer(test.version)
+		client, err := Open(server.URL)
+		if err != nil {
+			t.Fatalf("could not open client: %s", err)
+		}
+		if err := client.Ping(); err != nil {
+			t.Fatalf("could not ping: %s", err)
+		}
+		client.DateHistogramAggregate("index", "doc", nil, "date", test.interval, 0)
+		server.Close()
+		request := struct {
+			Aggs struct {
+				MyDateHistogram struct {
+					DateHistogram map[string]interface{} `json:"date_histogram"`
+				} `json:"my_datehistogram"`
+			} `json:"aggs"`
+		}{}
+		if err := json.Unmarshal([]byte(server.bodies[0]), &request); err != nil {
+			t.Fatalf("could not unmarshal request: %s", err)
+		}
+		if !reflect.DeepEqual(request.Aggs.MyDateHistogram.DateHistogram, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.version, test.expected, request.Aggs.MyDateHistogram.DateHistogram)
+		}
+	}
+}
+
+func TestComposableTemplate(t *testing.T) {
+	legacy := map[string]interface{}{
+		"index_patterns": []string{"logs-*"},
+		"order":          1,
+		"settings":       map[string]interface{}{"number_of_shards": 1},
+		"mappings":       map[string]interface{}{"properties": map[string]interface{}{}},
+	}
+	expected := map[string]interface{}{
+		"index_patterns": []string{"logs-*"},
+		"priority":       1,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{"number_of_shards": 1},
+			"mappings": map[string]interface{}{"properties": map[string]interface{}{}},
+		},
+	}
+	if result := composableTemplate(legacy); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+	if result := composableTemplate(expected); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("composable template was changed: %v", result)
+	}
+}
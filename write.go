@@ -0,0 +1,333 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// VersionType is the type of the version of a write, see WriteOptions.
+type VersionType string
+
+const (
+	// VersionTypeInternal writes only, if the version equals the current version
+	// of the document. It is only supported by Elasticsearch 6, use IfSeqNo and
+	// IfPrimaryTerm instead.
+	VersionTypeInternal VersionType = "internal"
+	// VersionTypeExternal writes only, if the version is higher than the current
+	// version of the document, and stores it as new version.
+	VersionTypeExternal VersionType = "external"
+	// VersionTypeExternalGTE writes only, if the version is higher than or equal to
+	// the current version of the document, and stores it as new version.
+	VersionTypeExternalGTE VersionType = "external_gte"
+)
+
+// WriteOptions contains the options for writing a document. They allow optimistic
+// concurrency control: If the document was changed in between, the write fails with
+// an error matching ErrVersionConflict, see IsVersionConflict.
+type WriteOptions struct {
+	Refresh Refresh
+	// IfSeqNo and IfPrimaryTerm write only, if the document has the sequence
+	// number and the primary term, e.g. from a Hit or a WriteResult. They are
+	// only used, if IfPrimaryTerm is set, since primary terms start with 1.
+	IfSeqNo       int64
+	IfPrimaryTerm int64
+	// Version is the version for the VersionType. It is only used, if VersionType is set.
+	Version     int64
+	VersionType VersionType
+	// ifVersion is set by IfMatch for documents without sequence number and primary term.
+	ifVersion bool
+}
+
+// query returns the query parameters for the options.
+func (o WriteOptions) query() string {
+	params := url.Values{}
+	params.Set("refresh", getRefreshString(o.Refresh))
+	if o.IfPrimaryTerm > 0 {
+		params.Set("if_seq_no", strconv.FormatInt(o.IfSeqNo, 10))
+		params.Set("if_primary_term", strconv.FormatInt(o.IfPrimaryTerm, 10))
+	}
+	if o.VersionType != "" {
+		params.Set("version", strconv.FormatInt(o.Version, 10))
+		params.Set("version_type", string(o.VersionType))
+	}
+	return "?" + params.Encode()
+}
+
+// idempotent returns true, if a write with the options can be repeated. A write
+// with a condition fails, if it is repeated after it succeeded.
+func (o WriteOptions) idempotent() bool {
+	return o.IfPrimaryTerm == 0 && o.VersionType == ""
+}
+
+// Result is the result of a write of a document.
+type Result string
+
+// Results of a write
+const (
+	ResultCreated  Result = "created"
+	ResultUpdated  Result = "updated"
+	ResultDeleted  Result = "deleted"
+	ResultNoop     Result = "noop"
+	ResultNotFound Result = "not_found"
+)
+
+// WriteResult is the result of a write of a document.
+type WriteResult struct {
+	ID          string `json:"_id"`
+	Index       string `json:"_index"`
+	Version     int64  `json:"_version"`
+	SeqNo       int64  `json:"_seq_no"`
+	PrimaryTerm int64  `json:"_primary_term"`
+	Result      Result `json:"result"`
+}
+
+// IfMatch returns write options, that only write, if the document was not changed
+// since this write.
+func (r *WriteResult) IfMatch(refresh Refresh) WriteOptions {
+	return ifMatch(refresh, r.Version, r.SeqNo, r.PrimaryTerm)
+}
+
+// ifMatch returns write options, that only write, if the document still has the
+// sequence number and primary term. Elasticsearch before 6.7 does not return them
+// for reads, the internal version is checked instead, see checkWriteOptions.
+func ifMatch(refresh Refresh, version, seqNo, primaryTerm int64) WriteOptions {
+	if primaryTerm > 0 {
+		return WriteOptions{Refresh: refresh, IfSeqNo: seqNo, IfPrimaryTerm: primaryTerm}
+	}
+	return WriteOptions{Refresh: refresh, Version: version, VersionType: VersionTypeInternal, ifVersion: true}
+}
+
+// checkWriteOptions returns an error, if the options were returned by IfMatch for a
+// document without sequence number and primary term, but Elasticsearch before 6.7
+// was not detected. Newer versions reject the internal version, and the document
+// was probably read before the version was detected.
+func (c *Client) checkWriteOptions(options WriteOptions) error {
+	if v := c.Version(); options.ifVersion && (v == Version{} || v.AtLeast(6, 7)) {
+		return errors.New("the document has no sequence number and primary term, detect the version with Ping before reading it")
+	}
+	return nil
+}
+
+// decodeWriteResult decodes the response of a write.
+func decodeWriteResult(b []byte) (*WriteResult, error) {
+	result := &WriteResult{}
+	if err := json.Unmarshal(b, result); err != nil {
+		return nil, fmt.Errorf("could not decode write result: %w", err)
+	}
+	return result, nil
+}
+
+// InsertDocumentWithOptions is like InsertDocument, but writes with the options
+// and returns the result of the write.
+func (c *Client) InsertDocumentWithOptions(index, doctype, id string, document map[string]interface{}, options WriteOptions) (*WriteResult, error) {
+	return c.InsertDocumentWithOptionsContext(context.Background(), index, doctype, id, document, options)
+}
+
+// InsertDocumentWithOptionsContext is like InsertDocumentWithOptions, but aborts the request when ctx is done.
+func (c *Client) InsertDocumentWithOptionsContext(ctx context.Context, index, doctype, id string, document map[string]interface{}, options WriteOptions) (*WriteResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "InsertDocument", index, doctype)
+	defer span.End()
+	if err := c.checkWriteOptions(options); err != nil {
+		return nil, fmt.Errorf("could not insert document: %w", err)
+	}
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the document: %w", err)
+	}
+	b, err = c.put(ctx, c.documentPath(index, doctype, id)+options.query(), b, options.idempotent())
+	if err != nil {
+		return nil, fmt.Errorf("could not insert document: %w", err)
+	}
+	return decodeWriteResult(b)
+}
+
+// CreateDocument inserts a document with the id in a specific index, but unlike InsertDocument,
+// it does not replace an existing document. If the id already exists, an error
+// matching ErrDocumentExists is returned, see IsDocumentExists.
+func (c *Client) CreateDocument(index, doctype, id string, document map[string]interface{}, refresh Refresh) (*WriteResult, error) {
+	return c.CreateDocumentContext(context.Background(), index, doctype, id, document, refresh)
+}
+
+// CreateDocumentContext is like CreateDocument, but aborts the request when ctx is done.
+// The request is not idempotent. With RetryPolicy.RetryNonIdempotent, a retry may
+// fail with ErrDocumentExists for the document created by the first attempt.
+func (c *Client) CreateDocumentContext(ctx context.Context, index, doctype, id string, document map[string]interface{}, refresh Refresh) (*WriteResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "CreateDocument", index, doctype)
+	defer span.End()
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the document: %w", err)
+	}
+	apipath := c.documentPath(index, doctype, id) + "?op_type=create&refresh=" + getRefreshString(refresh)
+	b, err = c.put(ctx, apipath, b, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not create document: %w", err)
+	}
+	return decodeWriteResult(b)
+}
+
+// IndexDocument inserts a document in a specific index with an id generated by
+// Elasticsearch. The id is returned in the ID of the result.
+func (c *Client) IndexDocument(index, doctype string, document map[string]interface{}, refresh Refresh) (*WriteResult, error) {
+	return c.IndexDocumentContext(context.Background(), index, doctype, document, refresh)
+}
+
+// IndexDocumentContext is like IndexDocument, but aborts the request when ctx is done.
+// The request is not idempotent. With RetryPolicy.RetryNonIdempotent, a retry may
+// insert the document twice.
+func (c *Client) IndexDocumentContext(ctx context.Context, index, doctype string, document map[string]interface{}, refresh Refresh) (*WriteResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "IndexDocument", index, doctype)
+	defer span.End()
+	b, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the document: %w", err)
+	}
+	apipath := c.documentPath(index, doctype, "") + "?refresh=" + getRefreshString(refresh)
+	b, err = c.post(ctx, apipath, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not index document: %w", err)
+	}
+	return decodeWriteResult(b)
+}
+
+// UpdateDocumentWithOptions is like UpdateDocument, but writes with the options
+// and returns the result of the write. The update api does not support external versions.
+func (c *Client) UpdateDocumentWithOptions(index, doctype, id string, painlessScript string, params map[string]interface{}, options WriteOptions) (*WriteResult, error) {
+	return c.UpdateDocumentWithOptionsContext(context.Background(), index, doctype, id, painlessScript, params, options)
+}
+
+// UpdateDocumentWithOptionsContext is like UpdateDocumentWithOptions, but aborts the request when ctx is done.
+func (c *Client) UpdateDocumentWithOptionsContext(ctx context.Context, index, doctype, id string, painlessScript string, params map[string]interface{}, options WriteOptions) (*WriteResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "UpdateDocument", index, doctype)
+	defer span.End()
+	if err := c.checkWriteOptions(options); err != nil {
+		return nil, fmt.Errorf("could not update document: %w", err)
+	}
+	script := map[string]interface{}{
+		"source": painlessScript,
+		"lang":   "painless",
+	}
+	if params != nil {
+		script["params"] = params
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"script": script,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the changes: %w", err)
+	}
+	b, err = c.post(ctx, c.updatePath(index, doctype, id)+options.query(), b)
+	if err != nil {
+		return nil, fmt.Errorf("could not update document: %w", err)
+	}
+	return decodeWriteResult(b)
+}
+
+// DocumentUpdate describes an update of a document, see UpdateDocumentPartial.
+// Either Doc or Script has to be set.
+type DocumentUpdate struct {
+	// Doc is a partial document, that is merged into the document.
+	Doc map[string]interface{}
+	// DocAsUpsert inserts Doc, if the document does not exist.
+	DocAsUpsert bool
+	// Script is a painless script updating the document in ctx._source with the Params.
+	Script string
+	Params map[string]interface{}
+	// Upsert is inserted, if the document does not exist. With ScriptedUpsert,
+	// the Script is run on Upsert instead.
+	Upsert         map[string]interface{}
+	ScriptedUpsert bool
+	// DisableDetectNoop writes the document, even if Doc does not change it.
+	// By default, the result is ResultNoop in this case.
+	DisableDetectNoop bool
+	// RetryOnConflict is the number of retries, if the document is changed by
+	// another request during the update. It cannot be used with IfSeqNo and IfPrimaryTerm.
+	RetryOnConflict int
+}
+
+// body returns the request body of the update.
+func (u *DocumentUpdate) body() map[string]interface{} {
+	body := map[string]interface{}{}
+	if u.Doc != nil {
+		body["doc"] = u.Doc
+	}
+	if u.DocAsUpsert {
+		body["doc_as_upsert"] = true
+	}
+	if u.Script != "" {
+		script := map[string]interface{}{
+			"source": u.Script,
+			"lang":   "painless",
+		}
+		if u.Params != nil {
+			script["params"] = u.Params
+		}
+		body["script"] = script
+	}
+	if u.Upsert != nil {
+		body["upsert"] = u.Upsert
+	}
+	if u.ScriptedUpsert {
+		body["scripted_upsert"] = true
+	}
+	if u.DisableDetectNoop {
+		body["detect_noop"] = false
+	}
+	return body
+}
+
+// UpdateDocumentPartial updates a document with a partial document or a script and
+// optionally inserts it, if it does not exist. The Result of the returned WriteResult
+// is ResultCreated, ResultUpdated, ResultDeleted or ResultNoop.
+func (c *Client) UpdateDocumentPartial(index, doctype, id string, update DocumentUpdate, options WriteOptions) (*WriteResult, error) {
+	return c.UpdateDocumentPartialContext(context.Background(), index, doctype, id, update, options)
+}
+
+// UpdateDocumentPartialContext is like UpdateDocumentPartial, but aborts the request when ctx is done.
+func (c *Client) UpdateDocumentPartialContext(ctx context.Context, index, doctype, id string, update DocumentUpdate, options WriteOptions) (*WriteResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "UpdateDocument", index, doctype)
+	defer span.End()
+	if update.Doc == nil && update.Script == "" {
+		return nil, errors.New("could not update document: doc or script is required")
+	}
+	if err := c.checkWriteOptions(options); err != nil {
+		return nil, fmt.Errorf("could not update document: %w", err)
+	}
+	b, err := json.Marshal(update.body())
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal the changes: %w", err)
+	}
+	apipath := c.updatePath(index, doctype, id) + options.query()
+	if update.RetryOnConflict > 0 {
+		apipath += "&retry_on_conflict=" + strconv.Itoa(update.RetryOnConflict)
+	}
+	b, err = c.post(ctx, apipath, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not update document: %w", err)
+	}
+	return decodeWriteResult(b)
+}
+
+// DeleteDocumentWithOptions is like DeleteDocument, but deletes with the options
+// and returns the result of the delete.
+func (c *Client) DeleteDocumentWithOptions(index, doctype, id string, options WriteOptions) (*WriteResult, error) {
+	return c.DeleteDocumentWithOptionsContext(context.Background(), index, doctype, id, options)
+}
+
+// DeleteDocumentWithOptionsContext is like DeleteDocumentWithOptions, but aborts the request when ctx is done.
+func (c *Client) DeleteDocumentWithOptionsContext(ctx context.Context, index, doctype, id string, options WriteOptions) (*WriteResult, error) {
+	ctx, span := c.startOperation(ctx, APIDocument, "DeleteDocument", index, doctype)
+	defer span.End()
+	if err := c.checkWriteOptions(options); err != nil {
+		return nil, fmt.Errorf("could not delete document: %w", err)
+	}
+	b, err := c.delete_(ctx, c.documentPath(index, doctype, id)+options.query(), nil, options.idempotent())
+	if err != nil {
+		return nil, fmt.Errorf("could not delete document: %w", err)
+	}
+	return decodeWriteResult(b)
+}
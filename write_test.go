@@ -0,0 +1,234 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/NextronSystems/go-elasticsearch/estest"
+)
+
+func TestClient_WriteOptions(t *testing.T) {
+	forEachVersion(t, fakeVersions, func(t *testing.T, server *estest.Server, client *Client) {
+		first, err := client.InsertDocumentWithOptions("occ", "doc", "1", map[string]interface{}{"count": 1}, WriteOptions{})
+		if err != nil {
+			t.Fatalf("could not insert document: %s", err)
+		}
+		if first.ID != "1" || first.Index != "occ" || first.Version != 1 || first.PrimaryTerm != 1 || first.Result != ResultCreated {
+			t.Fatalf("unexpected write result: %+v", first)
+		}
+		hit, err := Get[map[string]interface{}](client, "occ", "doc", "1")
+		if err != nil {
+			t.Fatalf("could not get document: %s", err)
+		}
+		second, err := client.UpdateDocumentWithOptions("occ", "doc", "1", "ctx._source.count += params.n", map[string]interface{}{"n": 1}, hit.IfMatch(RefreshFalse))
+		if err != nil {
+			t.Fatalf("could not update document: %s", err)
+		}
+		if second.Version != 2 || second.SeqNo <= first.SeqNo || second.Result != ResultUpdated {
+			t.Fatalf("unexpected update result: %+v", second)
+		}
+		// the document was changed since it was read
+		_, err = client.InsertDocumentWithOptions("occ", "doc", "1", map[string]interface{}{"count": 5}, hit.IfMatch(RefreshFalse))
+		if !IsVersionConflict(err) || !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected version conflict, got %v", err)
+		}
+		if _, err := client.DeleteDocumentWithOptions("occ", "doc", "1", first.IfMatch(RefreshFalse)); !IsVersionConflict(err) {
+			t.Fatalf("expected version conflict on delete, got %v", err)
+		}
+		deleted, err := client.DeleteDocumentWithOptions("occ", "doc", "1", second.IfMatch(RefreshTrue))
+		if err != nil || deleted.Result != ResultDeleted {
+			t.Fatalf("could not delete document: %v %+v", err, deleted)
+		}
+
+		external := WriteOptions{Version: 10, VersionType: VersionTypeExternal}
+		result, err := client.InsertDocumentWithOptions("occ", "doc", "2", map[string]interface{}{"count": 1}, external)
+		if err != nil || result.Version != 10 {
+			t.Fatalf("could not insert external version: %v %+v", err, result)
+		}
+		if _, err := client.InsertDocumentWithOptions("occ", "doc", "2", map[string]interface{}{"count": 2}, external); !IsVersionConflict(err) {
+			t.Fatalf("expected version conflict for equal external version, got %v", err)
+		}
+		external.VersionType = VersionTypeExternalGTE
+		if result, err := client.InsertDocumentWithOptions("occ", "doc", "2", map[string]interface{}{"count": 2}, external); err != nil || result.Version != 10 {
+			t.Fatalf("could not insert equal external_gte version: %v %+v", err, result)
+		}
+		internal := WriteOptions{Version: 10, VersionType: VersionTypeInternal}
+		_, err = client.InsertDocumentWithOptions("occ", "doc", "2", map[string]interface{}{"count": 3}, internal)
+		if !client.Version().AtLeast(7, 0) && err != nil {
+			t.Fatalf("could not insert with internal version: %s", err)
+		}
+		var esErr *ElasticsearchError
+		if client.Version().AtLeast(7, 0) && (!errors.As(err, &esErr) || esErr.StatusCode != 400) {
+			t.Fatalf("expected bad request for internal version, got %v", err)
+		}
+	})
+}
+
+func TestClient_WriteOptionsWithoutSeqNo(t *testing.T) {
+	// Elasticsearch before 6.7 does not return sequence numbers for reads.
+	forEachVersion(t, []string{"6.1.1"}, func(t *testing.T, server *estest.Server, client *Client) {
+		if _, err := client.InsertDocumentWithOptions("occ", "doc", "1", map[string]interface{}{"count": 1}, WriteOptions{Refresh: RefreshTrue}); err != nil {
+			t.Fatalf("could not insert document: %s", err)
+		}
+		hit, err := Get[map[string]interface{}](client, "occ", "doc", "1")
+		if err != nil {
+			t.Fatalf("could not get document: %s", err)
+		}
+		response, err := Search[map[string]interface{}](client, "occ", "doc", map[string]interface{}{})
+		if err != nil || len(response.Hits.Hits) != 1 {
+			t.Fatalf("could not search document: %v", err)
+		}
+		for _, options := range []WriteOptions{hit.IfMatch(RefreshFalse), response.Hits.Hits[0].IfMatch(RefreshFalse)} {
+			if options.VersionType != VersionTypeInternal || options.Version != 1 {
+				t.Fatalf("expected internal version, got %+v", options)
+			}
+		}
+		if _, err := client.UpdateDocumentWithOptions("occ", "doc", "1", "ctx._source.count += 1", nil, hit.IfMatch(RefreshFalse)); err != nil {
+			t.Fatalf("could not update document: %s", err)
+		}
+		// the document was changed since it was read
+		_, err = client.InsertDocumentWithOptions("occ", "doc", "1", map[string]interface{}{"count": 5}, response.Hits.Hits[0].IfMatch(RefreshFalse))
+		if !IsVersionConflict(err) {
+			t.Fatalf("expected version conflict, got %v", err)
+		}
+	})
+}
+
+func TestClient_IfMatchWithoutSeqNo(t *testing.T) {
+	hit := &Hit[map[string]interface{}]{ID: "1", Version: 1}
+	server, requests := statusServer(nil)
+	defer server.Close()
+	client, err := Open(server.URL)
+	if err != nil {
+		t.Fatalf("could not open client: %s", err)
+	}
+	// the version was not detected
+	if _, err := client.InsertDocumentWithOptions("occ", "doc", "1", map[string]interface{}{"count": 1}, hit.IfMatch(RefreshFalse)); err == nil {
+		t.Fatal("expected error for unknown version")
+	}
+	if *requests != 0 {
+		t.Fatalf("expected no request, got %d", *requests)
+	}
+	forEachVersion(t, []string{"7.17.0"}, func(t *testing.T, server *estest.Server, client *Client) {
+		if _, err := client.DeleteDocumentWithOptions("occ", "doc", "1", hit.IfMatch(RefreshFalse)); err == nil {
+			t.Fatal("expected error for hit without sequence number")
+		}
+		if _, err := client.UpdateDocumentPartial("occ", "doc", "1", DocumentUpdate{Doc: map[string]interface{}{"count": 2}}, hit.IfMatch(RefreshFalse)); err == nil {
+			t.Fatal("expected error for hit without sequence number")
+		}
+	})
+}
+
+func TestWriteOptions_Query(t *testing.T) {
+	tests := []struct {
+		options  WriteOptions
+		expected string
+	}{
+		{WriteOptions{}, "?refresh=false"},
+		{WriteOptions{Refresh: RefreshWaitFor, IfSeqNo: 0, IfPrimaryTerm: 1}, "?if_primary_term=1&if_seq_no=0&refresh=wait_for"},
+		{WriteOptions{IfSeqNo: 5}, "?refresh=false"},
+		{WriteOptions{Version: 3, VersionType: VersionTypeExternal}, "?refresh=false&version=3&version_type=external"},
+		{WriteOptions{Version: 3}, "?refresh=false"},
+	}
+	for _, test := range tests {
+		if query := test.options.query(); query != test.expected {
+			t.Errorf("%+v: expected %s, got %s", test.options, test.expected, query)
+		}
+	}
+}
+
+func TestClient_CreateAndIndexDocument(t *testing.T) {
+	forEachVersion(t, []string{"6.8.23", "7.17.0", "8.11.0"}, func(t *testing.T, server *estest.Server, client *Client) {
+		result, err := client.CreateDocument("create", "doc", "1", map[string]interface{}{"count": 1}, RefreshTrue)
+		if err != nil || result.Result != ResultCreated || result.Version != 1 {
+			t.Fatalf("could not create document: %v %+v", err, result)
+		}
+		_, err = client.CreateDocument("create", "doc", "1", map[string]interface{}{"count": 2}, RefreshTrue)
+		if !IsDocumentExists(err) || !IsVersionConflict(err) {
+			t.Fatalf("expected document exists error, got %v", err)
+		}
+		_, err = client.InsertDocumentWithOptions("create", "doc", "1", map[string]interface{}{"count": 2}, WriteOptions{IfSeqNo: 99, IfPrimaryTerm: 1})
+		if IsDocumentExists(err) || !IsVersionConflict(err) {
+			t.Fatalf("expected version conflict, but not document exists, got %v", err)
+		}
+		first, err := client.IndexDocument("create", "doc", map[string]interface{}{"count": 3}, RefreshTrue)
+		if err != nil || first.ID == "" || first.Result != ResultCreated {
+			t.Fatalf("could not index document: %v %+v", err, first)
+		}
+		second, err := client.IndexDocument("create", "doc", map[string]interface{}{"count": 3}, RefreshTrue)
+		if err != nil || second.ID == first.ID {
+			t.Fatalf("expected a new id: %v %+v", err, second)
+		}
+		doc, err := client.GetDocument("create", "doc", first.ID)
+		if err != nil || doc["_source"].(map[string]interface{})["count"] != json.Number("3") {
+			t.Fatalf("could not get indexed document: %v %v", err, doc)
+		}
+	})
+}
+
+func TestClient_UpdateDocumentPartial(t *testing.T) {
+	forEachVersion(t, fakeVersions, func(t *testing.T, server *estest.Server, client *Client) {
+		source := func(id string) map[string]interface{} {
+			t.Helper()
+			doc, err := client.GetDocument("partial", "doc", id)
+			if err != nil {
+				t.Fatalf("could not get document %s: %s", id, err)
+			}
+			return doc["_source"].(map[string]interface{})
+		}
+		update := func(id string, update DocumentUpdate, expected Result) {
+			t.Helper()
+			result, err := client.UpdateDocumentPartial("partial", "doc", id, update, WriteOptions{})
+			if err != nil {
+				t.Fatalf("could not update document %s: %s", id, err)
+			}
+			if result.Result != expected || result.ID != id {
+				t.Fatalf("expected %s, got %+v", expected, result)
+			}
+		}
+		_, err := client.UpdateDocumentPartial("partial", "doc", "1", DocumentUpdate{Doc: map[string]interface{}{"a": 1}}, WriteOptions{})
+		if !IsNotFound(err) {
+			t.Fatalf("expected not found for missing document, got %v", err)
+		}
+		update("1", DocumentUpdate{Doc: map[string]interface{}{"a": 1, "nested": map[string]interface{}{"x": 1}}, DocAsUpsert: true}, ResultCreated)
+		update("1", DocumentUpdate{Doc: map[string]interface{}{"b": 2, "nested": map[string]interface{}{"y": 2}}}, ResultUpdated)
+		if doc := source("1"); doc["a"] != json.Number("1") || doc["b"] != json.Number("2") || len(doc["nested"].(map[string]interface{})) != 2 {
+			t.Fatalf("partial document was not merged: %v", doc)
+		}
+		update("1", DocumentUpdate{Doc: map[string]interface{}{"b": 2}}, ResultNoop)
+		update("1", DocumentUpdate{Doc: map[string]interface{}{"b": 2}, DisableDetectNoop: true}, ResultUpdated)
+		update("2", DocumentUpdate{Script: "ctx._source.count += params.n", Params: map[string]interface{}{"n": 1}, Upsert: map[string]interface{}{"count": 10}}, ResultCreated)
+		update("2", DocumentUpdate{Script: "ctx._source.count += params.n", Params: map[string]interface{}{"n": 1}, Upsert: map[string]interface{}{"count": 10}, RetryOnConflict: 3}, ResultUpdated)
+		if doc := source("2"); doc["count"] != json.Number("11") {
+			t.Fatalf("expected count 11, got %v", doc)
+		}
+		update("3", DocumentUpdate{Script: "ctx._source.count += params.n", Params: map[string]interface{}{"n": 1}, Upsert: map[string]interface{}{"count": 10}, ScriptedUpsert: true}, ResultCreated)
+		if doc := source("3"); doc["count"] != json.Number("11") {
+			t.Fatalf("expected scripted upsert count 11, got %v", doc)
+		}
+		if _, err := client.UpdateDocumentPartial("partial", "doc", "3", DocumentUpdate{}, WriteOptions{}); err == nil {
+			t.Fatalf("expected error for empty update")
+		}
+	})
+}
+
+func TestDocumentUpdate_Body(t *testing.T) {
+	update := DocumentUpdate{
+		Script:            "ctx._source.count += params.n",
+		Params:            map[string]interface{}{"n": 1},
+		Upsert:            map[string]interface{}{"count": 0},
+		ScriptedUpsert:    true,
+		DisableDetectNoop: true,
+	}
+	b, _ := json.Marshal(update.body())
+	expected := `{"detect_noop":false,"script":{"lang":"painless","params":{"n":1},"source":"ctx._source.count += params.n"},"scripted_upsert":true,"upsert":{"count":0}}`
+	if string(b) != expected {
+		t.Fatalf("expected %s, got %s", expected, b)
+	}
+	update = DocumentUpdate{Doc: map[string]interface{}{"a": 1}, DocAsUpsert: true}
+	b, _ = json.Marshal(update.body())
+	if expected := `{"doc":{"a":1},"doc_as_upsert":true}`; string(b) != expected {
+		t.Fatalf("expected %s, got %s", expected, b)
+	}
+}